@@ -2,40 +2,192 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Processes []ProcessConfig `json:"processes"`
+	Processes   []ProcessConfig    `json:"processes"`
+	Profiles    map[string]Profile `json:"profiles"`    // optional
+	LogDir      string             `json:"logDir"`      // optional
+	LogMaxSize  ByteSize           `json:"logMaxSize"`  // optional
+	LogMaxFiles int                `json:"logMaxFiles"` // optional
+	DetachKey   string             `json:"detachKey"`   // optional, key that leaves attach mode
+
+	// Dir is the absolute directory of the config file, which relative
+	// paths in it are resolved against. It is set by LoadConfig.
+	Dir string `json:"-" toml:"-"`
 }
 
 type ProcessConfig struct {
-	Name        string          `json:"name"`        // required
-	Command     []string        `json:"command"`     // required for non process groups
-	Autorun     bool            `json:"autorun"`     // optional
-	Cwd         string          `json:"cwd"`         // optional
-	ReadyRegexp string          `json:"readyRegexp"` // optional
-	Children    []ProcessConfig `json:"children"`    // required for process groups
-	GroupType   string          `json:"groupType"`   // required for process groups
+	Name              string            `json:"name"`              // required
+	Command           []string          `json:"command"`           // required for non process groups
+	Autorun           bool              `json:"autorun"`           // optional
+	Cwd               string            `json:"cwd"`               // optional
+	LogFile           string            `json:"logFile"`           // optional
+	Scrollback        int               `json:"scrollback"`        // optional, lines kept in memory
+	Lossless          bool              `json:"lossless"`          // optional
+	Env               map[string]string `json:"env"`               // optional
+	EnvFile           []string          `json:"envFile"`           // optional
+	ReadyRegexp       string            `json:"readyRegexp"`       // optional
+	ReadyOnPort       bool              `json:"readyOnPort"`       // optional
+	ReadyCheck        *CheckConfig      `json:"readyCheck"`        // optional
+	LivenessCheck     *CheckConfig      `json:"livenessCheck"`     // optional
+	Pty               bool              `json:"pty"`               // optional
+	PreStart          [][]string        `json:"preStart"`          // optional, commands run before the command starts
+	PostStop          [][]string        `json:"postStop"`          // optional, commands run after the command exits
+	DependsOn         []string          `json:"dependsOn"`         // optional
+	Watch             *WatchConfig      `json:"watch"`             // optional
+	StopSignal        string            `json:"stopSignal"`        // optional
+	StopTimeout       Duration          `json:"stopTimeout"`       // optional
+	Restart           string            `json:"restart"`           // optional
+	MaxRestarts       int               `json:"maxRestarts"`       // optional
+	RestartBackoff    Duration          `json:"restartBackoff"`    // optional
+	RestartMaxBackoff Duration          `json:"restartMaxBackoff"` // optional
+	Children          []ProcessConfig   `json:"children"`          // required for process groups
+	GroupType         string            `json:"groupType"`         // required for process groups
+}
+
+// CheckConfig describes a probe run against a process. Exactly one of HTTP,
+// TCP or Command is set.
+type CheckConfig struct {
+	HTTP     string   `json:"http"`     // URL expected to answer with a 2xx status
+	TCP      string   `json:"tcp"`      // host:port expected to accept connections
+	Command  []string `json:"command"`  // command expected to exit with code 0
+	Interval Duration `json:"interval"` // optional, time between attempts
+	Timeout  Duration `json:"timeout"`  // optional
+}
+
+// Profile is a named set of processes that is started instead of the autorun
+// ones, along with changes to how they run.
+type Profile struct {
+	Processes []string            `json:"processes"` // required, names of processes or groups
+	Overrides map[string]Override `json:"overrides"` // optional, keyed by process or group name
+}
+
+// Override changes how a process runs while its profile is in use.
+type Override struct {
+	Env  map[string]string `json:"env"`  // optional, applied on top of the process's env
+	Args []string          `json:"args"` // optional, replaces the arguments after the command
+}
+
+// WatchConfig describes the files that cause a process to be restarted, or
+// run again, when they change.
+type WatchConfig struct {
+	Include  []string `json:"include"`  // optional, globs of files to watch, defaults to all files
+	Exclude  []string `json:"exclude"`  // optional, globs of files and directories to ignore
+	Debounce Duration `json:"debounce"` // optional, quiet time before acting on changes
+	Action   string   `json:"action"`   // optional, "restart" or "run"
+}
+
+// Duration is a time.Duration that is written in the config as a Go
+// duration string such as "500ms" or "10s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", string(text))
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
 }
 
+// ByteSize is a size in bytes that is written in the config as a number with
+// an optional unit, such as "512KB" or "10MB". Units are powers of 1024.
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(strings.TrimSpace(string(text)))
+	mult := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			mult = unit.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", string(text))
+	}
+	*b = ByteSize(n * mult)
+	return nil
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(b), 10)), nil
+}
+
+// LoadConfig reads the config file at path. The format is chosen from the
+// file's extension: YAML for .yaml and .yml, TOML for .toml, and JSON with
+// comments and trailing commas allowed for anything else.
 func LoadConfig(path string) (Config, error) {
 	config := Config{}
 
-	file, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return config, fmt.Errorf("config file '%s' does not exist", path)
 		}
 		return config, err
 	}
-	defer file.Close()
 
-	if err := json.NewDecoder(file).Decode(&config); err != nil {
+	if err := decode(path, data, &config); err != nil {
 		return config, err
 	}
 
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return config, err
+	}
+	config.Dir = dir
+
 	return config, nil
 }
+
+// Find returns the path of the first of Names that exists in dir or, failing
+// that, in the closest of its parents. The search stops at the root of the git
+// repository dir is in, or at the root of the filesystem.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		for _, name := range Names {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return "", fmt.Errorf("no config file found, looked for %s in this directory and its parents", strings.Join(Names, ", "))
+}
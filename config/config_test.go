@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadConfigValid(t *testing.T) {
@@ -47,3 +49,75 @@ func TestLoadConfigFileNotExist(t *testing.T) {
 		t.Fatal("expected error for missing file, got nil")
 	}
 }
+
+func TestLoadConfigStopTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.json")
+	content := `{"processes":[{"name":"api","command":["./api"],"stopSignal":"SIGINT","stopTimeout":"1m30s"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	conf, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	p := conf.Processes[0]
+	if p.StopSignal != "SIGINT" {
+		t.Errorf("unexpected stop signal: %q", p.StopSignal)
+	}
+	if time.Duration(p.StopTimeout) != 90*time.Second {
+		t.Errorf("unexpected stop timeout: %v", time.Duration(p.StopTimeout))
+	}
+}
+
+func TestLoadConfigInvalidDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.json")
+	content := `{"processes":[{"name":"api","command":["./api"],"stopTimeout":"soon"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for invalid duration, got nil")
+	}
+}
+
+func TestLoadConfigLogSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.json")
+	content := `{"logDir":"logs","logMaxSize":"512KB","logMaxFiles":3,"processes":[{"name":"api","command":["./api"],"logFile":"api.log"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	conf, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	if conf.LogDir != "logs" || conf.LogMaxFiles != 3 {
+		t.Errorf("unexpected log settings: %+v", conf)
+	}
+	if conf.LogMaxSize != 512*1024 {
+		t.Errorf("unexpected log max size: %d", conf.LogMaxSize)
+	}
+	if conf.Processes[0].LogFile != "api.log" {
+		t.Errorf("unexpected log file: %q", conf.Processes[0].LogFile)
+	}
+}
+
+func TestLoadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf.json")
+	if err := os.WriteFile(path, []byte(`{"processes":[]}`), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	conf, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if conf.Dir != dir {
+		t.Errorf("expected dir %q, got %q", dir, conf.Dir)
+	}
+}
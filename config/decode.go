@@ -0,0 +1,383 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Names are the config file names sheepdog looks for, in order of
+// preference.
+var Names = []string{
+	".sheepdog.json",
+	".sheepdog.jsonc",
+	".sheepdog.yaml",
+	".sheepdog.yml",
+	".sheepdog.toml",
+}
+
+// Error is a problem with a config file, pointing at where it was found.
+// Column is 0 when only the line is known.
+type Error struct {
+	Path   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+	case e.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Msg)
+	}
+}
+
+// decode decodes data into config, choosing the format from the extension
+// of path. JSON files may contain comments and trailing commas.
+func decode(path string, data []byte, config *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return decodeTOML(path, data, config)
+	case ".yaml", ".yml":
+		return decodeYAML(path, data, config)
+	default:
+		return decodeJSONC(path, data, config)
+	}
+}
+
+func decodeJSONC(path string, data []byte, config *Config) error {
+	data = stripJSONC(data)
+
+	err := json.Unmarshal(data, config)
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, col := position(data, int(syntaxErr.Offset)-1)
+		return &Error{Path: path, Line: line, Column: col, Msg: syntaxErr.Error()}
+	}
+
+	// encoding/json doesn't say which value it couldn't decode, so the file
+	// is decoded again the way YAML is, which knows where each value is
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if root, nodeErr := jsonNode(dec, data); nodeErr == nil {
+		if nodeErr := decodeRoot(path, root, &Config{}); nodeErr != nil {
+			return nodeErr
+		}
+	}
+	return &Error{Path: path, Msg: strings.TrimPrefix(err.Error(), "json: ")}
+}
+
+// jsonNode reads the next JSON value from dec, which reads data, into a
+// yaml.Node that has the position of the value and of everything in it.
+func jsonNode(dec *json.Decoder, data []byte) (*yaml.Node, error) {
+	start := int(dec.InputOffset())
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	// the offset is the end of the previous token, before the separators
+	for start < len(data) && strings.IndexByte(" \t\r\n,:", data[start]) >= 0 {
+		start++
+	}
+	line, col := position(data, start)
+	n := &yaml.Node{Line: line, Column: col}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		n.Kind = yaml.SequenceNode
+		if tok == '{' {
+			n.Kind = yaml.MappingNode
+		}
+		for dec.More() {
+			c, err := jsonNode(dec, data)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, c)
+		}
+		// the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.Kind, n.Tag, n.Value = yaml.ScalarNode, "!!str", tok
+	case json.Number:
+		n.Kind, n.Tag, n.Value = yaml.ScalarNode, "!!int", tok.String()
+		if strings.ContainsAny(n.Value, ".eE") {
+			n.Tag = "!!float"
+		}
+	case bool:
+		n.Kind, n.Tag, n.Value = yaml.ScalarNode, "!!bool", strconv.FormatBool(tok)
+	case nil:
+		n.Kind, n.Tag, n.Value = yaml.ScalarNode, "!!null", "null"
+	}
+	return n, nil
+}
+
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func decodeYAML(path string, data []byte, config *Config) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return &Error{Path: path, Line: line, Msg: m[2]}
+		}
+		return &Error{Path: path, Msg: strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	return decodeRoot(path, doc.Content[0], config)
+}
+
+// decodeRoot decodes the top-level node of the file at path into config.
+func decodeRoot(path string, root *yaml.Node, config *Config) error {
+	if err := decodeNode(root, reflect.ValueOf(config).Elem()); err != nil {
+		var configErr *Error
+		if errors.As(err, &configErr) {
+			configErr.Path = path
+		}
+		return err
+	}
+	return nil
+}
+
+var tomlLine = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+
+func decodeTOML(path string, data []byte, config *Config) error {
+	if _, err := toml.NewDecoder(bytes.NewReader(data)).Decode(config); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return &Error{Path: path, Line: parseErr.Position.Line, Column: parseErr.Position.Col, Msg: parseErr.Message}
+		}
+
+		// type mismatches only report the line and key they happened at
+		if m := tomlLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			keys := strings.Split(m[2], ".")
+			return &Error{Path: path, Line: line, Column: keyColumn(data, line, keys[len(keys)-1]), Msg: m[3]}
+		}
+		return &Error{Path: path, Msg: strings.TrimPrefix(err.Error(), "toml: ")}
+	}
+	return nil
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// decodeNode decodes n into v, matching mapping keys against the json tags
+// of struct fields the way encoding/json does: an exact match wins, otherwise
+// case is ignored. Unknown keys are ignored.
+func decodeNode(n *yaml.Node, v reflect.Value) error {
+	if n.Kind == yaml.AliasNode {
+		return decodeNode(n.Alias, v)
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return nil
+	}
+
+	if v.Kind() != reflect.Pointer && reflect.PointerTo(v.Type()).Implements(textUnmarshaler) {
+		if n.Kind != yaml.ScalarNode {
+			return nodeError(n, "expected a string")
+		}
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(n.Value)); err != nil {
+			return nodeError(n, err.Error())
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeNode(n, v.Elem())
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nodeError(n, "expected an object")
+		}
+		fields := make(map[string]int)
+		for i := range v.NumField() {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			if name != "" && name != "-" {
+				fields[name] = i
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			field, ok := fields[n.Content[i].Value]
+			if !ok {
+				for name, j := range fields {
+					if strings.EqualFold(name, n.Content[i].Value) {
+						field, ok = j, true
+						break
+					}
+				}
+			}
+			if !ok {
+				continue
+			}
+			if err := decodeNode(n.Content[i+1], v.Field(field)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return nodeError(n, "expected a list")
+		}
+		s := reflect.MakeSlice(v.Type(), len(n.Content), len(n.Content))
+		for i, c := range n.Content {
+			if err := decodeNode(c, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return nodeError(n, "expected an object")
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeNode(n.Content[i+1], elem); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(n.Content[i].Value).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+		return nil
+	}
+
+	if n.Kind != yaml.ScalarNode {
+		return nodeError(n, fmt.Sprintf("expected a %s", v.Kind()))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(n.Value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(n.Value)
+		if err != nil || n.Tag != "!!bool" {
+			return nodeError(n, fmt.Sprintf("expected true or false, got %q", n.Value))
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(n.Value, 10, 64)
+		if err != nil || n.Tag != "!!int" {
+			return nodeError(n, fmt.Sprintf("expected a whole number, got %q", n.Value))
+		}
+		v.SetInt(i)
+	default:
+		return nodeError(n, fmt.Sprintf("unsupported type %s", v.Type()))
+	}
+	return nil
+}
+
+func nodeError(n *yaml.Node, msg string) error {
+	return &Error{Line: n.Line, Column: n.Column, Msg: msg}
+}
+
+// stripJSONC blanks out the comments and trailing commas JSONC allows, so
+// that the result is plain JSON with everything still at its original line
+// and column.
+func stripJSONC(data []byte) []byte {
+	out := bytes.Clone(data)
+
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	lastComma := -1
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			lastComma = -1
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := bytes.IndexByte(out[i:], '\n')
+			if end < 0 {
+				end = len(out) - i
+			}
+			blank(i, i+end)
+			i += end - 1
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				// leave the unterminated comment for the parser to report
+				return out
+			}
+			blank(i, i+2+end+2)
+			i += 2 + end + 1
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			lastComma = -1
+		}
+	}
+	return out
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int) (int, int) {
+	offset = max(0, min(offset, len(data)))
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	col := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, col
+}
+
+// keyColumn finds the column of the value assigned to key on a line, or 0 if
+// it isn't there.
+func keyColumn(data []byte, line int, key string) int {
+	lines := strings.Split(string(data), "\n")
+	if line < 1 || line > len(lines) {
+		return 0
+	}
+
+	text := lines[line-1]
+	i := strings.Index(text, key)
+	if i < 0 {
+		return 0
+	}
+	eq := strings.Index(text[i:], "=")
+	if eq < 0 {
+		return i + 1
+	}
+	j := i + eq + 1
+	for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+		j++
+	}
+	return j + 1
+}
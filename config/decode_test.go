@@ -0,0 +1,233 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	want := Config{
+		LogMaxSize: 1024,
+		Processes: []ProcessConfig{
+			{
+				Name:        "web",
+				GroupType:   "parallel",
+				Env:         map[string]string{"PORT": "8080"},
+				StopTimeout: Duration(10 * time.Second),
+				Children: []ProcessConfig{
+					{Name: "api", Command: []string{"./api", "-v"}, Autorun: true, MaxRestarts: 3, ReadyCheck: &CheckConfig{TCP: "localhost:8080"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{".sheepdog.json", `{
+  // comments are allowed
+  "logMaxSize": "1KB",
+  "processes": [
+    {
+      "name": "web", /* and block comments */
+      "groupType": "parallel",
+      "env": {"PORT": "8080",},
+      "stopTimeout": "10s",
+      "children": [
+        {"name": "api", "command": ["./api", "-v",], "autorun": true, "maxRestarts": 3, "readyCheck": {"tcp": "localhost:8080"}},
+      ],
+    },
+  ],
+}`},
+		{".sheepdog.yaml", `
+logMaxSize: 1KB
+processes:
+  - name: web
+    groupType: parallel
+    env:
+      PORT: "8080"
+    stopTimeout: 10s
+    children:
+      - name: api
+        command: [./api, -v]
+        autorun: true
+        maxRestarts: 3
+        readyCheck:
+          tcp: localhost:8080
+`},
+		{".sheepdog.toml", `
+logMaxSize = "1KB"
+
+[[processes]]
+name = "web"
+groupType = "parallel"
+env = { PORT = "8080" }
+stopTimeout = "10s"
+
+[[processes.children]]
+name = "api"
+command = ["./api", "-v"]
+autorun = true
+maxRestarts = 3
+readyCheck = { tcp = "localhost:8080" }
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := LoadConfig(writeConfig(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("LoadConfig returned error: %v", err)
+			}
+			conf.Dir = ""
+			if !reflect.DeepEqual(conf, want) {
+				t.Fatalf("unexpected config:\n got %+v\nwant %+v", conf, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigJSONEscapes(t *testing.T) {
+	path := writeConfig(t, ".sheepdog.json", `{"processes": [
+		// the escapes JSON has that YAML doesn't, and a few it shares
+		{"name": "a\/b", "command": ["echo", "tab\tquote\" \u00e9 \ud83d\udc11"], "cwd": "C:\\dev"},
+	]}`)
+
+	conf, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	p := conf.Processes[0]
+	if p.Name != "a/b" || p.Cwd != `C:\dev` {
+		t.Fatalf("unexpected process: %+v", p)
+	}
+	if want := "tab\tquote\" é 🐑"; p.Command[1] != want {
+		t.Fatalf("expected %q, got %q", want, p.Command[1])
+	}
+}
+
+func TestLoadConfigKeysIgnoreCase(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{".sheepdog.json", `{"Processes": [{"Name": "api", "COMMAND": ["./api"], "stopTimeout": "2s"}]}`},
+		{".sheepdog.yaml", "Processes:\n  - Name: api\n    COMMAND: [./api]\n    stopTimeout: 2s\n"},
+		{".sheepdog.toml", "[[Processes]]\nName = \"api\"\nCOMMAND = [\"./api\"]\nstopTimeout = \"2s\"\n"},
+	}
+
+	want := []ProcessConfig{{Name: "api", Command: []string{"./api"}, StopTimeout: Duration(2 * time.Second)}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := LoadConfig(writeConfig(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("LoadConfig returned error: %v", err)
+			}
+			if !reflect.DeepEqual(conf.Processes, want) {
+				t.Fatalf("unexpected processes:\n got %+v\nwant %+v", conf.Processes, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrorPositions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+	}{
+		{".sheepdog.json", "{\n  \"processes\": [\n    {\"name\": \"api\" \"command\": []}\n  ]\n}", 3, 20},
+		{".sheepdog.json", "{\n  // timeout\n  \"processes\": [{\"name\": \"api\", \"stopTimeout\": \"soon\"}]\n}", 3, 48},
+		{".sheepdog.json", "{\"processes\": [{\"name\": \"api\",\n  \"maxRestarts\": \"x\"}]}", 2, 18},
+		{".sheepdog.json", "{\"processes\": [{\"name\": \"api\",\n  \"MaxRestarts\": 1.5}]}", 2, 18},
+		{".sheepdog.yaml", "processes:\n  - name: api\n    autorun: maybe\n", 3, 14},
+		{".sheepdog.toml", "[[processes]]\nname = \"api\"\nstopTimeout = \"soon\"\n", 3, 16},
+		{".sheepdog.toml", "[[processes]]\nname = \"api\"\nmaxRestarts = \"x\"\n", 3, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.name, tt.content)
+			_, err := LoadConfig(path)
+			configErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("expected *Error, got %T: %v", err, err)
+			}
+			if configErr.Path != path || configErr.Line != tt.line || configErr.Column != tt.column {
+				t.Fatalf("expected error at %s:%d:%d, got %v", path, tt.line, tt.column, err)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	// keep the search from leaving the temp dir
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	if _, err := Find(dir); err == nil {
+		t.Fatal("expected error when no config exists, got nil")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".sheepdog.toml"), nil, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".sheepdog.yml"), nil, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	path, err := Find(dir)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if filepath.Base(path) != ".sheepdog.yml" {
+		t.Fatalf("expected .sheepdog.yml to be preferred, got %q", path)
+	}
+}
+
+func TestFindParent(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "services", "api")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create dirs: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+
+	// outside the repository, so it must not be found
+	if err := os.WriteFile(filepath.Join(root, ".sheepdog.json"), nil, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if path, err := Find(sub); err == nil {
+		t.Fatalf("expected the search to stop at the git root, found %q", path)
+	}
+
+	want := filepath.Join(repo, ".sheepdog.yaml")
+	if err := os.WriteFile(want, nil, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	path, err := Find(sub)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if path != want {
+		t.Fatalf("expected %q, got %q", want, path)
+	}
+}
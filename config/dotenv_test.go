@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# database
+DB_HOST=localhost
+export DB_PORT=5432
+DB_NAME = app # trailing comment
+GREETING="hello\nworld"
+RAW='keep \n as is'
+EMPTY=
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	vars, err := LoadEnvFile(path)
+	if err != nil {
+		t.Fatalf("LoadEnvFile returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"DB_HOST":  "localhost",
+		"DB_PORT":  "5432",
+		"DB_NAME":  "app",
+		"GREETING": "hello\nworld",
+		"RAW":      `keep \n as is`,
+		"EMPTY":    "",
+	}
+	if len(vars) != len(expected) {
+		t.Fatalf("expected %d vars, got %d: %#v", len(expected), len(vars), vars)
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, vars[k])
+		}
+	}
+}
+
+func TestLoadEnvFileInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("FOO=bar\nnot a var\n"), 0644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	if _, err := LoadEnvFile(path); err == nil {
+		t.Fatal("expected error for invalid line, got nil")
+	}
+}
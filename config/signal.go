@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strings"
+	"syscall"
+)
+
+// stopSignal is a signal a process may be stopped with.
+type stopSignal struct {
+	name   string
+	signal syscall.Signal
+}
+
+// StopSignal returns the signal a stopSignal from the config names, which may
+// leave out the SIG prefix and be in any case, and whether it is one a process
+// may be stopped with.
+func StopSignal(name string) (syscall.Signal, bool) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for _, s := range stopSignals {
+		if s.name == name {
+			return s.signal, true
+		}
+	}
+	return 0, false
+}
+
+// stopSignalNames returns the names of the signals a process may be stopped
+// with.
+func stopSignalNames() []string {
+	names := make([]string, len(stopSignals))
+	for i, s := range stopSignals {
+		names[i] = s.name
+	}
+	return names
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package config
+
+import "syscall"
+
+// stopSignals are the signals a process may be stopped with.
+var stopSignals = []stopSignal{
+	{"SIGTERM", syscall.SIGTERM},
+	{"SIGINT", syscall.SIGINT},
+	{"SIGHUP", syscall.SIGHUP},
+	{"SIGQUIT", syscall.SIGQUIT},
+	{"SIGKILL", syscall.SIGKILL},
+	{"SIGUSR1", syscall.SIGUSR1},
+	{"SIGUSR2", syscall.SIGUSR2},
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package config
+
+import "syscall"
+
+// stopSignals are the signals a process may be stopped with. Windows has no
+// real signals, so anything other than SIGKILL asks the process tree to close
+// before it is forcefully terminated, and there are no user signals.
+var stopSignals = []stopSignal{
+	{"SIGTERM", syscall.SIGTERM},
+	{"SIGINT", syscall.SIGINT},
+	{"SIGHUP", syscall.SIGHUP},
+	{"SIGQUIT", syscall.SIGQUIT},
+	{"SIGKILL", syscall.SIGKILL},
+}
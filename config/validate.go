@@ -0,0 +1,299 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Problem is one thing wrong with a config. Path locates it in the process
+// tree, e.g. processes[2].children[0].readyRegexp.
+type Problem struct {
+	Path string
+	Msg  string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Msg
+	}
+	return p.Path + ": " + p.Msg
+}
+
+// ValidationError holds every problem Validate found.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the config for mistakes that would keep processes from
+// running as intended. It returns a *ValidationError listing all of them, or
+// nil if there are none.
+func Validate(config Config) error {
+	v := validator{paths: make(map[string]string)}
+
+	for i, p := range config.Processes {
+		v.process(p, fmt.Sprintf("processes[%d]", i))
+	}
+	if config.LogMaxFiles < 0 {
+		v.add("logMaxFiles", "must not be negative")
+	}
+
+	v.dependencies(config.Processes)
+	v.profiles(config)
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+type validator struct {
+	problems []Problem
+	// paths maps every process name to where it was first defined.
+	paths map[string]string
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) process(p ProcessConfig, at string) {
+	switch prev, ok := v.paths[p.Name]; {
+	case p.Name == "":
+		v.add(at, "name is required")
+	case ok:
+		v.add(at, "duplicate name %q, already used by %s", p.Name, prev)
+	default:
+		v.paths[p.Name] = at
+	}
+
+	isCommand := len(p.Command) > 0
+	isGroup := len(p.Children) > 0
+	switch {
+	case isCommand && isGroup:
+		v.add(at, "has both a command and children; a process is either a command or a group")
+	case !isCommand && !isGroup:
+		v.add(at, "needs either a command or children")
+	case isGroup && p.GroupType == "":
+		v.add(at, "groupType is required for a group")
+	case isGroup && p.GroupType != "sequential" && p.GroupType != "parallel":
+		v.add(at+".groupType", "must be \"sequential\" or \"parallel\", got %q", p.GroupType)
+	}
+
+	if p.ReadyRegexp != "" {
+		if _, err := regexp.Compile(p.ReadyRegexp); err != nil {
+			v.add(at+".readyRegexp", "invalid regexp: %v", err)
+		}
+	}
+	if p.ReadyCheck != nil {
+		v.check(*p.ReadyCheck, at+".readyCheck")
+	}
+	if p.LivenessCheck != nil {
+		v.check(*p.LivenessCheck, at+".livenessCheck")
+	}
+
+	for i, hook := range p.PreStart {
+		v.hook(hook, isGroup, fmt.Sprintf("%s.preStart[%d]", at, i))
+	}
+	for i, hook := range p.PostStop {
+		v.hook(hook, isGroup, fmt.Sprintf("%s.postStop[%d]", at, i))
+	}
+
+	if p.Scrollback < 0 {
+		v.add(at+".scrollback", "must not be negative")
+	}
+
+	if p.StopSignal != "" {
+		if _, ok := StopSignal(p.StopSignal); !ok {
+			v.add(at+".stopSignal", "unknown signal %q, expected one of %s", p.StopSignal, strings.Join(stopSignalNames(), ", "))
+		}
+	}
+
+	switch p.Restart {
+	case "", "never", "on-failure", "always":
+	default:
+		v.add(at+".restart", "must be \"never\", \"on-failure\" or \"always\", got %q", p.Restart)
+	}
+	if p.MaxRestarts < 0 {
+		v.add(at+".maxRestarts", "must not be negative")
+	}
+
+	if p.Watch != nil {
+		for i, pattern := range p.Watch.Include {
+			if _, err := path.Match(pattern, ""); err != nil {
+				v.add(fmt.Sprintf("%s.watch.include[%d]", at, i), "invalid pattern %q", pattern)
+			}
+		}
+		for i, pattern := range p.Watch.Exclude {
+			if _, err := path.Match(pattern, ""); err != nil {
+				v.add(fmt.Sprintf("%s.watch.exclude[%d]", at, i), "invalid pattern %q", pattern)
+			}
+		}
+		switch p.Watch.Action {
+		case "", "restart", "run":
+		default:
+			v.add(at+".watch.action", "must be \"restart\" or \"run\", got %q", p.Watch.Action)
+		}
+	}
+
+	for i, cp := range p.Children {
+		v.process(cp, fmt.Sprintf("%s.children[%d]", at, i))
+	}
+}
+
+func (v *validator) check(c CheckConfig, at string) {
+	kinds := 0
+	for _, set := range []bool{c.HTTP != "", c.TCP != "", len(c.Command) > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		v.add(at, "exactly one of http, tcp or command must be set")
+	}
+}
+
+// hook reports a preStart or postStop command that is empty, or set on a
+// group, which has no command of its own to run it around.
+func (v *validator) hook(command []string, isGroup bool, at string) {
+	switch {
+	case isGroup:
+		v.add(at, "hooks can only be set on a command, not a group")
+	case len(command) == 0:
+		v.add(at, "must not be empty")
+	}
+}
+
+// profiles reports profiles that name unknown processes or are empty.
+func (v *validator) profiles(config Config) {
+	groups := make(map[string]bool)
+	var walk func(ps []ProcessConfig)
+	walk = func(ps []ProcessConfig) {
+		for _, p := range ps {
+			if len(p.Children) > 0 {
+				groups[p.Name] = true
+			}
+			walk(p.Children)
+		}
+	}
+	walk(config.Processes)
+
+	for _, name := range slices.Sorted(maps.Keys(config.Profiles)) {
+		profile := config.Profiles[name]
+		at := "profiles." + name
+
+		if len(profile.Processes) == 0 {
+			v.add(at+".processes", "must name at least one process")
+		}
+		for i, pname := range profile.Processes {
+			if _, ok := v.paths[pname]; !ok {
+				v.add(fmt.Sprintf("%s.processes[%d]", at, i), "unknown process %q", pname)
+			}
+		}
+
+		for _, pname := range slices.Sorted(maps.Keys(profile.Overrides)) {
+			switch _, ok := v.paths[pname]; {
+			case !ok:
+				v.add(at+".overrides."+pname, "unknown process %q", pname)
+			case groups[pname] && profile.Overrides[pname].Args != nil:
+				v.add(at+".overrides."+pname+".args", "%q is a group; args can only be overridden for a command", pname)
+			}
+		}
+	}
+}
+
+// dependencies reports unknown names in dependsOn and dependency cycles. A
+// group implicitly depends on its children, so a process may not depend on
+// the group it belongs to.
+func (v *validator) dependencies(processes []ProcessConfig) {
+	type node struct {
+		name string
+		at   string
+		deps []string
+	}
+	nodes := make(map[string]*node)
+
+	var collect func(ps []ProcessConfig, at string)
+	collect = func(ps []ProcessConfig, at string) {
+		for i, p := range ps {
+			pat := fmt.Sprintf("%s[%d]", at, i)
+			if _, ok := nodes[p.Name]; ok || p.Name == "" {
+				// already reported as a duplicate or missing name
+				collect(p.Children, pat+".children")
+				continue
+			}
+
+			n := &node{name: p.Name, at: pat}
+			for j, dep := range p.DependsOn {
+				if _, ok := v.paths[dep]; !ok {
+					v.add(fmt.Sprintf("%s.dependsOn[%d]", pat, j), "unknown process %q", dep)
+					continue
+				}
+				n.deps = append(n.deps, dep)
+			}
+			for _, cp := range p.Children {
+				n.deps = append(n.deps, cp.Name)
+			}
+			nodes[p.Name] = n
+			collect(p.Children, pat+".children")
+		}
+	}
+	collect(processes, "processes")
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(nodes))
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		n, ok := nodes[name]
+		if !ok {
+			return
+		}
+
+		switch state[name] {
+		case visited:
+			return
+		case visiting:
+			i := len(stack) - 1
+			for i > 0 && stack[i] != name {
+				i--
+			}
+			cycle := append(append([]string{}, stack[i:]...), name)
+			v.add(n.at, "dependency cycle: %s", strings.Join(cycle, " -> "))
+			return
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range n.deps {
+			visit(dep)
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+
+	var walk func(ps []ProcessConfig)
+	walk = func(ps []ProcessConfig) {
+		for _, p := range ps {
+			visit(p.Name)
+			walk(p.Children)
+		}
+	}
+	walk(processes)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateValid(t *testing.T) {
+	conf := Config{Processes: []ProcessConfig{
+		{Name: "web", GroupType: "sequential", Children: []ProcessConfig{
+			{Name: "api", Command: []string{"./api"}, DependsOn: []string{"db"}, ReadyRegexp: "^listening"},
+		}},
+		{Name: "db", Command: []string{"pg"}, StopSignal: "int", Restart: "on-failure"},
+	}}
+
+	if err := Validate(conf); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+}
+
+func TestValidateCollectsProblems(t *testing.T) {
+	conf := Config{Processes: []ProcessConfig{
+		{Name: "web", GroupType: "paralel", PreStart: [][]string{{"make"}}, Children: []ProcessConfig{
+			{Name: "api", Command: []string{"./api"}, ReadyRegexp: "(["},
+			{Name: "api", Command: []string{"./api"}, ReadyCheck: &CheckConfig{}},
+		}},
+		{Name: "db", Command: []string{"pg"}, Children: []ProcessConfig{{Name: "x", Command: []string{"x"}}}, GroupType: "parallel"},
+		{Name: "worker", Command: []string{"./worker"}, DependsOn: []string{"queue"}, PostStop: [][]string{{}}, StopSignal: "SIGWINCH", Restart: "sometimes"},
+		{Command: []string{"./nameless"}},
+	}}
+
+	err := Validate(conf)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+
+	var paths []string
+	for _, p := range validationErr.Problems {
+		paths = append(paths, p.Path)
+	}
+	want := []string{
+		"processes[0].groupType",
+		"processes[0].preStart[0]",
+		"processes[0].children[0].readyRegexp",
+		"processes[0].children[1]",
+		"processes[0].children[1].readyCheck",
+		"processes[1]",
+		"processes[2].postStop[0]",
+		"processes[2].stopSignal",
+		"processes[2].restart",
+		"processes[3]",
+		"processes[2].dependsOn[0]",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("unexpected problem paths:\n got %q\nwant %q\n%v", paths, want, err)
+	}
+}
+
+func TestValidateDependencyCycle(t *testing.T) {
+	conf := Config{Processes: []ProcessConfig{
+		{Name: "web", GroupType: "parallel", Children: []ProcessConfig{
+			{Name: "api", Command: []string{"./api"}, DependsOn: []string{"db"}},
+		}},
+		{Name: "db", Command: []string{"pg"}, DependsOn: []string{"web"}},
+	}}
+
+	err := Validate(conf)
+	validationErr, ok := err.(*ValidationError)
+	if !ok || len(validationErr.Problems) != 1 {
+		t.Fatalf("expected a single problem, got %v", err)
+	}
+	if got := validationErr.Problems[0].String(); got != "processes[0]: dependency cycle: web -> api -> db -> web" {
+		t.Fatalf("unexpected problem: %q", got)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	conf := Config{
+		Processes: []ProcessConfig{
+			{Name: "web", GroupType: "parallel", Children: []ProcessConfig{
+				{Name: "api", Command: []string{"./api"}},
+			}},
+		},
+		Profiles: map[string]Profile{
+			"backend": {
+				Processes: []string{"api", "queue"},
+				Overrides: map[string]Override{
+					"web": {Args: []string{"-v"}},
+					"db":  {Env: map[string]string{"PORT": "1"}},
+				},
+			},
+			"empty": {},
+			"frontend": {
+				Processes: []string{"web"},
+				Overrides: map[string]Override{"api": {Args: []string{"--mock"}}},
+			},
+		},
+	}
+
+	err := Validate(conf)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+
+	var paths []string
+	for _, p := range validationErr.Problems {
+		paths = append(paths, p.Path)
+	}
+	want := []string{
+		"profiles.backend.processes[1]",
+		"profiles.backend.overrides.db",
+		"profiles.backend.overrides.web.args",
+		"profiles.empty.processes",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("unexpected problem paths:\n got %q\nwant %q\n%v", paths, want, err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package control implements the socket that lets scripts drive a running
+// Sheepdog instance.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SocketName is the name of the control socket, created next to the config
+// file.
+const SocketName = ".sheepdog.sock"
+
+// replyTimeout bounds how long a connection waits for the program to answer.
+const replyTimeout = 10 * time.Second
+
+const (
+	VerbList    = "list"
+	VerbStatus  = "status"
+	VerbStart   = "start"
+	VerbStop    = "stop"
+	VerbRestart = "restart"
+	VerbLogs    = "logs"
+)
+
+type Request struct {
+	Verb  string `json:"verb"`
+	Name  string `json:"name,omitempty"`
+	Lines int    `json:"lines,omitempty"`
+}
+
+type Response struct {
+	Error     string          `json:"error,omitempty"`
+	Processes []ProcessStatus `json:"processes,omitempty"`
+	Lines     []string        `json:"lines,omitempty"`
+}
+
+type ProcessStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	IsGroup bool   `json:"isGroup,omitempty"`
+	Depth   int    `json:"depth,omitempty"`
+}
+
+// Message carries a request into the Bubble Tea program. Whoever handles it
+// must send exactly one Response on Reply.
+type Message struct {
+	Request Request
+	Reply   chan<- Response
+}
+
+// SocketPath returns the path of the control socket for a config file.
+func SocketPath(configPath string) string {
+	dir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		dir = filepath.Dir(configPath)
+	}
+	return filepath.Join(dir, SocketName)
+}
+
+type Server struct {
+	path     string
+	listener net.Listener
+	send     func(Message)
+}
+
+// Listen creates the control socket at path. Requests are handed to send,
+// which is expected to forward them into the running program. A socket left
+// behind by a previous instance is replaced, but one that still answers is
+// reported as an error.
+func Listen(path string, send func(Message)) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another sheepdog is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{path: path, listener: l, send: send}, nil
+}
+
+// Serve accepts connections until the server is closed.
+func (s *Server) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("control socket stopped accepting connections", "error", err)
+			}
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	reply := make(chan Response, 1)
+	s.send(Message{Request: req, Reply: reply})
+
+	var res Response
+	select {
+	case res = <-reply:
+	case <-time.After(replyTimeout):
+		res = Response{Error: "timed out waiting for sheepdog to respond"}
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		slog.Error("failed to write control response", "error", err)
+	}
+}
+
+// Close stops the server and removes the socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	os.Remove(s.path)
+	return err
+}
+
+// Do sends a single request to the sheepdog listening on path.
+func Do(path string, req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return Response{}, fmt.Errorf("sheepdog does not appear to be running: %v", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+
+	var res Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return Response{}, err
+	}
+	if res.Error != "" {
+		return res, errors.New(res.Error)
+	}
+	return res, nil
+}
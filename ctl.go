@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/steventhorne/sheepdog/control"
+)
+
+const ctlUsage = `usage: sheepdog ctl <command> [name] [-n lines]
+
+Commands:
+  list             show every process and its status
+  status <name>    show the status of a process or group
+  start <name>     run a process or group
+  stop <name>      stop a process or group
+  restart <name>   stop a process or group and run it again
+  logs <name>      print the most recent log lines of a process
+`
+
+// runCtl implements `sheepdog ctl`, which drives an already running sheepdog
+// through its control socket.
+func runCtl(configPath string, args []string) int {
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	lines := fs.Int("n", 0, "number of log lines to print for logs")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), ctlUsage)
+	}
+
+	// allow flags after the positional arguments, e.g. `ctl logs api -n 20`
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) == 0 || len(positional) > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	req := control.Request{Verb: positional[0], Lines: *lines}
+	if len(positional) == 2 {
+		req.Name = positional[1]
+	}
+
+	res, err := control.Do(control.SocketPath(configPath), req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sheepdog: %v\n", err)
+		return 1
+	}
+
+	if len(res.Processes) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, p := range res.Processes {
+			fmt.Fprintf(w, "%s%s\t%s\n", strings.Repeat("  ", p.Depth), p.Name, p.Status)
+		}
+		w.Flush()
+	}
+	for _, line := range res.Lines {
+		fmt.Println(line)
+	}
+
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/control"
+	"github.com/steventhorne/sheepdog/model"
+)
+
+// runHeadless runs the selected processes, or the autorun ones, without the
+// TUI and returns the exit code: 1 if any process errored.
+func runHeadless(configPath string, conf config.Config, selection model.Selection) int {
+	m, err := model.NewHeadlessModel(conf, selection, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sheepdog: %v\n", err)
+		return 2
+	}
+
+	p := tea.NewProgram(m, tea.WithoutRenderer(), tea.WithInput(nil), tea.WithoutSignalHandler())
+	m.SetProgram(p)
+
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		for range sig {
+			p.Send(model.ShutdownMsg{})
+		}
+	}()
+
+	srv, err := control.Listen(control.SocketPath(configPath), func(msg control.Message) {
+		p.Send(msg)
+	})
+	if err != nil {
+		slog.Warn("control socket disabled", "error", err)
+	} else {
+		go srv.Serve()
+		defer srv.Close()
+	}
+
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sheepdog: %v\n", err)
+		return 1
+	}
+	if f, ok := final.(interface{ Failed() bool }); ok && f.Failed() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/control"
+	"github.com/steventhorne/sheepdog/model"
+)
+
+func TestRunHeadlessExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		code    int
+	}{
+		{"success", []string{"true"}, 0},
+		{"failure", []string{"sh", "-c", "exit 3"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			conf := config.Config{Dir: dir, Processes: []config.ProcessConfig{
+				{Name: "task", Command: tt.command, Autorun: true},
+			}}
+
+			code := runHeadless(filepath.Join(dir, ".sheepdog.yaml"), conf, model.Selection{})
+			if code != tt.code {
+				t.Fatalf("exit code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestRunHeadlessShutsDownOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".sheepdog.yaml")
+	conf := config.Config{Dir: dir, Processes: []config.ProcessConfig{
+		{Name: "server", Command: []string{"sleep", "30"}, Autorun: true},
+	}}
+
+	done := make(chan int)
+	go func() {
+		done <- runHeadless(configPath, conf, model.Selection{})
+	}()
+
+	// the control socket is created once the signals are being handled
+	socket := control.SocketPath(configPath)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the control socket")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("failed to send SIGTERM: %v", err)
+	}
+
+	select {
+	case code := <-done:
+		if code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("sheepdog did not shut down on SIGTERM")
+	}
+}
@@ -8,8 +8,24 @@ type KeyMap struct {
 	Down  key.Binding
 	Run   key.Binding
 	Kill  key.Binding
+	Env   key.Binding
 	Quit  key.Binding
 	Enter key.Binding
+
+	Profiles key.Binding
+	CopyURL  key.Binding
+
+	Attach    key.Binding
+	SendInput key.Binding
+
+	History key.Binding
+
+	Search      key.Binding
+	Filter      key.Binding
+	NextMatch   key.Binding
+	PrevMatch   key.Binding
+	StderrOnly  key.Binding
+	ClearSearch key.Binding
 }
 
 var DefaultKeyMap = KeyMap{
@@ -29,6 +45,10 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("x"),
 		key.WithHelp("x", "kill process"),
 	),
+	Env: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "toggle environment"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("ctrl+c"),
 		key.WithHelp("ctrl+c", "quit"),
@@ -37,4 +57,48 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("enter"),
 		key.WithHelp("enter", "enter"),
 	),
+	Profiles: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "switch profile"),
+	),
+	CopyURL: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy URL"),
+	),
+	Attach: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "attach to process"),
+	),
+	SendInput: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "send a line of input"),
+	),
+	History: key.NewBinding(
+		key.WithKeys("h"),
+		key.WithHelp("h", "show older lines on disk"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search log"),
+	),
+	Filter: key.NewBinding(
+		key.WithKeys("&"),
+		key.WithHelp("&", "filter log"),
+	),
+	NextMatch: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "next match"),
+	),
+	PrevMatch: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "previous match"),
+	),
+	StderrOnly: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "toggle stderr only"),
+	),
+	ClearSearch: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "clear search and filter"),
+	),
 }
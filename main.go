@@ -2,14 +2,19 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/control"
 	"github.com/steventhorne/sheepdog/model"
 )
 
@@ -28,11 +33,137 @@ func resolveVersion() string {
 	return version
 }
 
+// findConfig picks the config file to use: the one given with --config, then
+// the one in SHEEPDOG_CONFIG, then the closest one found by config.Find.
+func findConfig(flagPath string) (string, error) {
+	if flagPath != "" {
+		return flagPath, nil
+	}
+	if envPath := os.Getenv("SHEEPDOG_CONFIG"); envPath != "" {
+		return envPath, nil
+	}
+	return config.Find(".")
+}
+
+// listFlag collects comma-separated values from a flag that may be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// subcommands are the commands sheepdog runs instead of starting processes
+// when one is the first argument after the flags.
+var subcommands = map[string]bool{"ctl": true, "validate": true}
+
+// parseArgs parses the flags in args, which may come before or after the
+// process names, e.g. `sheepdog api --except worker`. If the first name is a
+// subcommand, it is returned with the arguments after it left unparsed for the
+// subcommand to parse. Everything after `--` is a process name, so processes
+// named like a subcommand can be given as `sheepdog -- ctl`.
+func parseArgs(fs *flag.FlagSet, args []string) (names []string, sub string, subArgs []string, err error) {
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, "", nil, err
+		}
+		rest := fs.Args()
+		if endsFlags(fs, args[:len(args)-fs.NArg()]) {
+			return append(names, rest...), "", nil, nil
+		}
+		if len(rest) == 0 {
+			return names, "", nil, nil
+		}
+		if len(names) == 0 && subcommands[rest[0]] {
+			return nil, rest[0], rest[1:], nil
+		}
+		names = append(names, rest[0])
+		args = rest[1:]
+	}
+}
+
+// endsFlags reports whether the flags fs parsed from parsed were ended by
+// `--`. The flag package drops it from the remaining arguments, and a `--`
+// that is the value of a flag, as in `--profile --`, doesn't end them.
+func endsFlags(fs *flag.FlagSet, parsed []string) bool {
+	for i := 0; i < len(parsed); i++ {
+		if parsed[i] == "--" {
+			return true
+		}
+		name := strings.TrimLeft(parsed[i], "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
+		}
+		// the next argument is the value
+		i++
+	}
+	return false
+}
+
 func main() {
-	title := "Sheepdog"
-	fmt.Printf("\033]0;%s\007", title)
+	var only, except listFlag
+	flag.Var(&only, "only", "start only these processes instead of the autorun ones (comma-separated names or globs)")
+	flag.Var(&except, "except", "don't start these processes (comma-separated names or globs)")
+	profile := flag.String("profile", "", "start the processes of this profile from the config, with its overrides")
+	var configFlag string
+	flag.StringVar(&configFlag, "config", "", "path to the config file (default: search this directory and its parents)")
+	flag.StringVar(&configFlag, "c", "", "shorthand for --config")
+	headless := flag.Bool("headless", false, "run without the TUI, streaming output to stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: sheepdog [-c file] [--headless] [--profile name | --only a,b] [--except c,d] [--] [process ...]\n       sheepdog [-c file] ctl <command> [name]\n       sheepdog [-c file] validate [file ...]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	names, sub, subArgs, err := parseArgs(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		// the flag package has printed the error and the usage already
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
 
-	f, err := os.OpenFile(".sheepdog.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	configPath, findErr := findConfig(configFlag)
+
+	switch sub {
+	case "ctl":
+		if findErr != nil {
+			// the socket is found by directory, so any name will do
+			configPath = config.Names[0]
+		}
+		os.Exit(runCtl(configPath, subArgs))
+	case "validate":
+		os.Exit(runValidate(configPath, findErr, subArgs))
+	}
+
+	if !*headless {
+		title := "Sheepdog"
+		fmt.Printf("\033]0;%s\007", title)
+	}
+
+	if findErr != nil {
+		fmt.Fprintf(os.Stderr, "sheepdog: %v\n", findErr)
+		os.Exit(1)
+	}
+
+	// next to the config file, like the control socket, wherever sheepdog
+	// was started
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(configPath), model.DebugLogName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		log.Fatalf("failed to open log file: %v", err)
 	}
@@ -41,13 +172,44 @@ func main() {
 	handler := slog.NewTextHandler(f, nil)
 	slog.SetDefault(slog.New(handler))
 
-	conf, err := config.LoadConfig(".sheepdog.json")
+	conf, ok := loadConfig(os.Stderr, configPath)
+	if !ok {
+		os.Exit(1)
+	}
+
+	// process names given as arguments are the same as --only
+	selection := model.Selection{
+		Profile: *profile,
+		Only:    append(only, names...),
+		Except:  except,
+	}
+
+	if *headless {
+		os.Exit(runHeadless(configPath, conf, selection))
+	}
+
+	m, err := model.NewModel(conf, selection, resolveVersion())
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "sheepdog: %v\n", err)
+		os.Exit(2)
 	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	m.SetProgram(p)
 
-	m := model.NewModel(conf, resolveVersion())
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+	srv, err := control.Listen(control.SocketPath(configPath), func(msg control.Message) {
+		p.Send(msg)
+	})
+	if err != nil {
+		slog.Warn("control socket disabled", "error", err)
+	} else {
+		go srv.Serve()
+	}
+
+	_, err = p.Run()
+	if srv != nil {
+		srv.Close()
+	}
+	if err != nil {
 		fmt.Printf("Whoops, there was an error: %v\n", err)
 		os.Exit(1)
 	}
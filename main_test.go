@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		names   []string
+		sub     string
+		subArgs []string
+		profile string
+		except  []string
+	}{
+		{args: "api worker", names: []string{"api", "worker"}},
+		{args: "api --except worker", names: []string{"api"}, except: []string{"worker"}},
+		{args: "--headless api -except a,b db", names: []string{"api", "db"}, except: []string{"a", "b"}},
+		{args: "ctl logs api -n 20", sub: "ctl", subArgs: []string{"logs", "api", "-n", "20"}},
+		{args: "--headless validate", sub: "validate", subArgs: []string{}},
+		{args: "api ctl", names: []string{"api", "ctl"}},
+		{args: "-- ctl --except", names: []string{"ctl", "--except"}},
+		{args: "--headless -- validate", names: []string{"validate"}},
+		{args: "--profile -- api", names: []string{"api"}, profile: "--"},
+		{args: "--profile=-- -- ctl", names: []string{"ctl"}, profile: "--"},
+		{args: "api -- --except x", names: []string{"api", "--except", "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			fs := flag.NewFlagSet("sheepdog", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			var except listFlag
+			fs.Var(&except, "except", "")
+			profile := fs.String("profile", "", "")
+			fs.Bool("headless", false, "")
+
+			names, sub, subArgs, err := parseArgs(fs, strings.Fields(tt.args))
+			if err != nil {
+				t.Fatalf("parseArgs returned error: %v", err)
+			}
+			if !slices.Equal(names, tt.names) || sub != tt.sub || !slices.Equal(subArgs, tt.subArgs) {
+				t.Fatalf("got names %q, subcommand %q with %q", names, sub, subArgs)
+			}
+			if *profile != tt.profile || !slices.Equal(except, tt.except) {
+				t.Fatalf("got profile %q, except %q", *profile, except)
+			}
+		})
+	}
+}
+
+func TestParseArgsBadFlag(t *testing.T) {
+	fs := flag.NewFlagSet("sheepdog", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, _, _, err := parseArgs(fs, []string{"api", "--bogus"}); err == nil {
+		t.Fatal("expected an error for an unknown flag")
+	}
+}
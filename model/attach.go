@@ -0,0 +1,187 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultDetachKey is the key that leaves attach mode when the config
+// doesn't set one.
+const defaultDetachKey = "ctrl+]"
+
+// inputWriteTimeout is how long writing input may block on a command that
+// isn't reading it before the input is given up on.
+const inputWriteTimeout = time.Second
+
+// keySequences are the bytes a terminal sends for keys that aren't a
+// character or control code of their own.
+var keySequences = map[tea.KeyType]string{
+	tea.KeyUp:       "\x1b[A",
+	tea.KeyDown:     "\x1b[B",
+	tea.KeyRight:    "\x1b[C",
+	tea.KeyLeft:     "\x1b[D",
+	tea.KeyHome:     "\x1b[H",
+	tea.KeyEnd:      "\x1b[F",
+	tea.KeyInsert:   "\x1b[2~",
+	tea.KeyDelete:   "\x1b[3~",
+	tea.KeyPgUp:     "\x1b[5~",
+	tea.KeyPgDown:   "\x1b[6~",
+	tea.KeyShiftTab: "\x1b[Z",
+}
+
+// keyBytes returns the bytes a terminal would send for a key, or nil for
+// keys it can't be sent as.
+func keyBytes(msg tea.KeyMsg) []byte {
+	var b []byte
+	switch {
+	case msg.Type == tea.KeyRunes:
+		b = []byte(string(msg.Runes))
+	case msg.Type == tea.KeySpace:
+		b = []byte(" ")
+	case msg.Type >= 0 && msg.Type <= 127:
+		// control codes, including enter, tab, esc and backspace, are
+		// their own key type
+		b = []byte{byte(msg.Type)}
+	default:
+		seq, ok := keySequences[msg.Type]
+		if !ok {
+			return nil
+		}
+		b = []byte(seq)
+	}
+
+	if msg.Alt && !msg.Paste {
+		b = append([]byte{0x1b}, b...)
+	}
+	return b
+}
+
+// writeInput writes data to the command's stdin, or its terminal.
+func (m *process) writeInput(data []byte) error {
+	if m.stdin == nil || !m.status.isActive() {
+		return fmt.Errorf("%s isn't running", m.name)
+	}
+
+	// a command that doesn't read its input would block the whole UI
+	_ = m.stdin.SetWriteDeadline(time.Now().Add(inputWriteTimeout))
+	_, err := m.stdin.Write(data)
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return fmt.Errorf("%s isn't reading its input", m.name)
+	case errors.Is(err, os.ErrClosed):
+		return fmt.Errorf("the input of %s is closed", m.name)
+	}
+	return err
+}
+
+// sendLine writes a line of input, ending it the way enter would.
+func (m *process) sendLine(line string) error {
+	if m.pty {
+		return m.writeInput([]byte(line + "\r"))
+	}
+	return m.writeInput([]byte(line + "\n"))
+}
+
+// logInputError reports input that couldn't be sent in the process's log.
+func (m *process) logInputError(err error) {
+	m.logEvent(logEntry{
+		msg:   fmt.Sprintf("input not sent: %v", err),
+		level: logError,
+	})
+	m.loadViewportFromInbox()
+}
+
+// attach starts forwarding keys to the selected process and shows it full
+// screen.
+func (m *processList) attach() tea.Cmd {
+	p := m.selectedProcess
+	if p == nil || p.isGroup {
+		return nil
+	}
+	if p.stdin == nil || !p.status.isActive() {
+		p.logInputError(fmt.Errorf("%s isn't running", p.name))
+		return nil
+	}
+
+	m.attached = p
+	m.promptText = ""
+	p.isFocused = true
+	p.prompt = m.attachStatus()
+	return nil
+}
+
+func (m *processList) detach() {
+	m.attached.prompt = ""
+	m.attached = nil
+	m.promptText = ""
+}
+
+// attachStatus is shown below the log of the attached process. Processes
+// without a terminal are sent a line at a time, so the line being typed is
+// shown too.
+func (m *processList) attachStatus() string {
+	status := fmt.Sprintf("attached, %s to detach", m.detachKey)
+	if m.attached.pty {
+		return status
+	}
+	return fmt.Sprintf("%s  > %s█", status, m.promptText)
+}
+
+// updateAttached handles a key pressed while attached to a process. A
+// terminal gets every key as it is pressed. A pipe gets the line once enter
+// is pressed, like a terminal that isn't in raw mode would send it, and
+// ctrl+d closes it.
+func (m *processList) updateAttached(msg tea.KeyMsg) tea.Cmd {
+	p := m.attached
+	if msg.String() == m.detachKey {
+		m.detach()
+		return nil
+	}
+
+	if p.pty {
+		b := keyBytes(msg)
+		if b == nil {
+			return nil
+		}
+		if err := p.writeInput(b); err != nil {
+			m.detach()
+			p.logInputError(err)
+		}
+		return nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEnter:
+		err := p.sendLine(m.promptText)
+		m.promptText = ""
+		if err != nil {
+			m.detach()
+			p.logInputError(err)
+			return nil
+		}
+	case tea.KeyCtrlD:
+		p.logEvent(logEntry{
+			msg:   "closed stdin",
+			level: logInfo,
+		})
+		p.stdin.Close()
+		m.detach()
+		p.loadViewportFromInbox()
+		return nil
+	case tea.KeyBackspace:
+		if runes := []rune(m.promptText); len(runes) > 0 {
+			m.promptText = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.promptText += " "
+	case tea.KeyRunes:
+		m.promptText += string(msg.Runes)
+	}
+
+	p.prompt = m.attachStatus()
+	return nil
+}
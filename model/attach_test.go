@@ -0,0 +1,73 @@
+package model
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func TestKeyBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		key  tea.KeyMsg
+		want string
+	}{
+		{"runes", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("rs")}, "rs"},
+		{"space", tea.KeyMsg{Type: tea.KeySpace}, " "},
+		{"enter", tea.KeyMsg{Type: tea.KeyEnter}, "\r"},
+		{"ctrl+c", tea.KeyMsg{Type: tea.KeyCtrlC}, "\x03"},
+		{"up", tea.KeyMsg{Type: tea.KeyUp}, "\x1b[A"},
+		{"alt", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true}, "\x1bb"},
+		{"unknown", tea.KeyMsg{Type: tea.KeyF1}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(keyBytes(tt.key)); got != tt.want {
+				t.Errorf("keyBytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachSendsLines(t *testing.T) {
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "api", Command: []string{"./api"}},
+	}})
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	p := pl.byName["api"]
+	p.stdin = w
+	p.status = statusRunning
+
+	pl.attach()
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("rx")},
+		{Type: tea.KeyBackspace},
+		{Type: tea.KeyRunes, Runes: []rune("s")},
+		{Type: tea.KeyEnter},
+	} {
+		pl.Update(msg)
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "rs\n" {
+		t.Fatalf("expected the typed line to be sent, got %q", line)
+	}
+
+	pl.Update(tea.KeyMsg{Type: tea.KeyCtrlCloseBracket})
+	if pl.attached != nil || p.prompt != "" {
+		t.Fatal("expected the detach key to detach")
+	}
+}
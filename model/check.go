@@ -0,0 +1,188 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// defaultCheckInterval is the time between two attempts of a check when the
+// config doesn't set one.
+const defaultCheckInterval = time.Second
+
+// check is a probe used to decide whether a process is ready, or whether a
+// ready process is still healthy.
+type check struct {
+	url      string
+	address  string
+	command  []string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func newCheck(conf *config.CheckConfig) (*check, error) {
+	kinds := 0
+	for _, set := range []bool{conf.HTTP != "", conf.TCP != "", len(conf.Command) > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, errors.New("exactly one of http, tcp or command must be set")
+	}
+
+	c := &check{
+		url:      conf.HTTP,
+		address:  conf.TCP,
+		command:  conf.Command,
+		interval: defaultCheckInterval,
+		timeout:  time.Duration(conf.Timeout),
+	}
+	if conf.Interval > 0 {
+		c.interval = time.Duration(conf.Interval)
+	}
+
+	return c, nil
+}
+
+func (c *check) String() string {
+	switch {
+	case c.url != "":
+		return "GET " + c.url
+	case c.address != "":
+		return "tcp " + c.address
+	default:
+		return fmt.Sprintf("%q", c.command)
+	}
+}
+
+// probe makes a single attempt of the check, giving up after timeout.
+func (c *check) probe(ctx context.Context, dir string, env []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch {
+	case c.url != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %s", res.Status)
+		}
+		return nil
+	case c.address != "":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", c.address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+		cmd.Dir = dir
+		cmd.Env = env
+		return cmd.Run()
+	}
+}
+
+// waitReady probes until the check passes, reporting the process as ready
+// and waking it up with wake. If the check has a timeout and it expires first,
+// the process is stopped with the reason as its cancel cause.
+func (c *check) waitReady(ctx context.Context, cancel context.CancelCauseFunc, dir string, env []string, statusCh chan processStatus, wake func()) {
+	var deadline <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.probe(ctx, dir, env, c.interval); err == nil {
+			if ctx.Err() == nil {
+				statusCh <- statusReady
+				wake()
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			statusCh <- statusStopping
+			wake()
+			cancel(fmt.Errorf("ready check %s did not pass within %s", c, c.timeout))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchLiveness probes a ready process until ctx is done, flipping it to
+// statusUnhealthy while the check fails and back to statusReady once it
+// passes again. wake is called after every change.
+func (c *check) watchLiveness(ctx context.Context, dir string, env []string, inboxCh chan logEntry, statusCh chan processStatus, wake func()) {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = c.interval
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := c.probe(ctx, dir, env, timeout)
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err != nil && healthy:
+			healthy = false
+			entry := logEntry{
+				msg:   fmt.Sprintf("liveness check %s failed: %v", c, err),
+				level: logError,
+			}
+			select {
+			case inboxCh <- entry:
+			default:
+			}
+			statusCh <- statusUnhealthy
+			wake()
+		case err == nil && !healthy:
+			healthy = true
+			entry := logEntry{
+				msg:   fmt.Sprintf("liveness check %s passed", c),
+				level: logInfo,
+			}
+			select {
+			case inboxCh <- entry:
+			default:
+			}
+			statusCh <- statusReady
+			wake()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func TestCheckProbe(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(ok.Close)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closed.Close()
+
+	tests := []struct {
+		name string
+		conf config.CheckConfig
+		pass bool
+	}{
+		{"http ok", config.CheckConfig{HTTP: ok.URL}, true},
+		{"http failing status", config.CheckConfig{HTTP: failing.URL}, false},
+		{"tcp listening", config.CheckConfig{TCP: ln.Addr().String()}, true},
+		{"tcp closed", config.CheckConfig{TCP: closed.Addr().String()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCheck(&tt.conf)
+			if err != nil {
+				t.Fatalf("newCheck returned error: %v", err)
+			}
+			err = c.probe(context.Background(), "", nil, time.Second)
+			if tt.pass && err != nil {
+				t.Fatalf("expected the check to pass, got %v", err)
+			}
+			if !tt.pass && err == nil {
+				t.Fatal("expected the check to fail")
+			}
+		})
+	}
+}
+
+func TestCheckWaitReadyPasses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	c, err := newCheck(&config.CheckConfig{HTTP: srv.URL, Interval: config.Duration(10 * time.Millisecond)})
+	if err != nil {
+		t.Fatalf("newCheck returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	statusCh := make(chan processStatus, 1)
+	c.waitReady(ctx, cancel, "", nil, statusCh, func() {})
+
+	if status := <-statusCh; status != statusReady {
+		t.Fatalf("expected the process to be reported ready, got %v", status)
+	}
+	if context.Cause(ctx) != nil {
+		t.Fatalf("expected the process not to be stopped, got %v", context.Cause(ctx))
+	}
+}
+
+func TestProcessStatusStringsHaveTheSameWidth(t *testing.T) {
+	want := len(statusIdle.String())
+	for s := statusIdle; s <= statusErrored+1; s++ {
+		if got := len(s.String()); got != want {
+			t.Errorf("%q is %d wide, want %d", s.String(), got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+//go:build !windows
+
+package model
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// waitForStatus pulls in what arrives for p until it has the status want,
+// failing the test if that takes too long.
+func waitForStatus(t *testing.T, p *process, want processStatus) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		p.Update(processMsg{id: p.id})
+		if p.status == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %s to become %v, got %v", p.name, want, p.status)
+}
+
+func TestReadyCheckTimeoutErrorsProcess(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closed.Close()
+
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{{
+		Name:    "api",
+		Command: []string{"sleep", "30"},
+		ReadyCheck: &config.CheckConfig{
+			TCP:      closed.Addr().String(),
+			Interval: config.Duration(10 * time.Millisecond),
+			Timeout:  config.Duration(100 * time.Millisecond),
+		},
+	}}})
+	p := pl.byName["api"]
+	t.Cleanup(p.Cancel)
+
+	p.Run()
+	if p.status != statusRunning {
+		t.Fatalf("expected api to run until the check passes, got %v", p.status)
+	}
+	waitForStatus(t, p, statusErrored)
+
+	entries := p.log.entries()
+	if len(entries) == 0 || !strings.Contains(entries[len(entries)-1].msg, "did not pass within 100ms") {
+		t.Fatalf("expected the timeout to be logged, got %#v", entries)
+	}
+}
+
+func TestLivenessCheckFlipsProcessToUnhealthyAndBack(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{{
+		Name:    "api",
+		Command: []string{"sleep", "30"},
+		LivenessCheck: &config.CheckConfig{
+			HTTP:     srv.URL,
+			Interval: config.Duration(10 * time.Millisecond),
+		},
+	}}})
+	p := pl.byName["api"]
+	t.Cleanup(p.Cancel)
+
+	p.Run()
+	if p.status != statusReady {
+		t.Fatalf("expected api to be ready, got %v", p.status)
+	}
+
+	healthy.Store(false)
+	waitForStatus(t, p, statusUnhealthy)
+
+	healthy.Store(true)
+	waitForStatus(t, p, statusReady)
+
+	var msgs []string
+	for _, e := range p.log.entries() {
+		msgs = append(msgs, e.msg)
+	}
+	log := strings.Join(msgs, "\n")
+	if !strings.Contains(log, "failed: unexpected status 503") || !strings.Contains(log, "passed") {
+		t.Fatalf("expected the liveness changes to be logged, got %q", log)
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyToClipboard asks the terminal to put text on the clipboard with an
+// OSC 52 sequence, which also works over SSH. Inside tmux or screen the
+// sequence is wrapped so that it reaches the outer terminal.
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		switch {
+		case os.Getenv("TMUX") != "":
+			seq = seq.Tmux()
+		case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+			seq = seq.Screen()
+		}
+		// stderr is the same terminal, without racing the renderer's
+		// writes to stdout
+		seq.WriteTo(os.Stderr)
+		return nil
+	}
+}
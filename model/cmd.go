@@ -3,19 +3,36 @@ package model
 import (
 	"context"
 	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
 )
 
 type Cmd struct {
-	ctx        context.Context
-	terminated chan struct{}
+	ctx         context.Context
+	terminated  chan struct{}
+	stopSignal  syscall.Signal
+	stopTimeout time.Duration
+	// terminal is set when stdin is a pseudo-terminal that should become
+	// the command's controlling terminal.
+	terminal bool
 	*exec.Cmd
 }
 
+// lookupStopSignal returns the signal a stopSignal from the config names, and
+// whether it is one a process may be stopped with.
+func lookupStopSignal(name string) (syscall.Signal, bool) {
+	return config.StopSignal(name)
+}
+
 func NewCommand(ctx context.Context, command string, args ...string) *Cmd {
 	return &Cmd{
-		ctx:        ctx,
-		terminated: make(chan struct{}),
-		Cmd:        exec.Command(command, args...),
+		ctx:         ctx,
+		terminated:  make(chan struct{}),
+		stopSignal:  syscall.SIGTERM,
+		stopTimeout: defaultStopTimeout,
+		Cmd:         exec.Command(command, args...),
 	}
 }
 
@@ -36,11 +53,38 @@ func (c *Cmd) Start() error {
 		if p == nil {
 			return
 		}
-		c.killProcessTree()
+		c.stopProcessTree()
 	}()
 	return nil
 }
 
+// stopProcessTree asks the process tree to exit with the stop signal and
+// kills it if it is still running once the stop timeout has passed. The
+// command exiting doesn't end the wait for the rest of the tree, which may
+// have ignored the signal.
+func (c *Cmd) stopProcessTree() {
+	if err := c.signalProcessTree(c.stopSignal); err != nil {
+		c.killProcessTree()
+		return
+	}
+
+	timeout := time.After(c.stopTimeout)
+	select {
+	case <-c.terminated:
+	case <-timeout:
+		c.killProcessTree()
+		return
+	}
+
+	if !c.processTreeAlive() {
+		return
+	}
+	<-timeout
+	if c.processTreeAlive() {
+		c.killProcessTree()
+	}
+}
+
 func (c *Cmd) Run() error {
 	if err := c.Start(); err != nil {
 		return err
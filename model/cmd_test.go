@@ -0,0 +1,22 @@
+package model
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestLookupStopSignal(t *testing.T) {
+	tests := map[string]syscall.Signal{
+		"SIGTERM": syscall.SIGTERM,
+		"sigint":  syscall.SIGINT,
+		"kill":    syscall.SIGKILL,
+	}
+	for name, want := range tests {
+		if got, ok := lookupStopSignal(name); !ok || got != want {
+			t.Errorf("lookupStopSignal(%q) = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+	if _, ok := lookupStopSignal("SIGSTOP"); ok {
+		t.Error("expected SIGSTOP not to be a stop signal")
+	}
+}
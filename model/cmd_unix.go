@@ -3,21 +3,51 @@
 package model
 
 import (
+	"os"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
+// exitSignal returns the name of the signal that terminated a process, or ""
+// if it exited on its own.
+func exitSignal(state *os.ProcessState) string {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return ""
+	}
+	return unix.SignalName(ws.Signal())
+}
+
 func (c *Cmd) setProcessGroup() {
+	if c.terminal {
+		// a new session leads its own process group too, and is needed
+		// for the terminal to become the controlling one
+		c.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+		return
+	}
 	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
-func (c *Cmd) killProcessTree() error {
+// signalProcessTree sends sig to the process group of the command. The
+// command leads its own group, so the group is still found by its pid after
+// the command itself has exited.
+func (c *Cmd) signalProcessTree(sig syscall.Signal) error {
 	if c.Process == nil {
 		return nil
 	}
+	return syscall.Kill(-c.Process.Pid, sig)
+}
 
-	pgid, err := syscall.Getpgid(c.Process.Pid)
-	if err != nil {
-		return c.Process.Kill()
+// processTreeAlive reports whether anything is left in the process group of
+// the command.
+func (c *Cmd) processTreeAlive() bool {
+	if c.Process == nil {
+		return false
 	}
-	return syscall.Kill(-pgid, syscall.SIGKILL)
+	return syscall.Kill(-c.Process.Pid, 0) != syscall.ESRCH
+}
+
+func (c *Cmd) killProcessTree() error {
+	return c.signalProcessTree(syscall.SIGKILL)
 }
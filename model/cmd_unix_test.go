@@ -0,0 +1,119 @@
+//go:build !windows
+
+package model
+
+import (
+	"bufio"
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStopProcessTreeSignalsBeforeKilling(t *testing.T) {
+	const stopTimeout = 300 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		signal syscall.Signal
+		trap   string
+		killed bool
+	}{
+		{"survives SIGTERM", syscall.SIGTERM, "TERM", true},
+		{"survives SIGUSR1", syscall.SIGUSR1, "USR1", true},
+		{"exits on SIGTERM", syscall.SIGTERM, "TERM", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := "echo trapped"
+			if !tt.killed {
+				handler += "; exit 0"
+			}
+			script := "trap '" + handler + "' " + tt.trap + "; echo up; while :; do sleep 0.05; done"
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cmd := NewCommand(ctx, "sh", "-c", script)
+			cmd.stopSignal = tt.signal
+			cmd.stopTimeout = stopTimeout
+			out, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatalf("StdoutPipe returned error: %v", err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("Start returned error: %v", err)
+			}
+			lines := bufio.NewScanner(out)
+			if !lines.Scan() || lines.Text() != "up" {
+				t.Fatalf("expected the trap to be set up, got %q", lines.Text())
+			}
+
+			stopped := time.Now()
+			cancel()
+			if !lines.Scan() || lines.Text() != "trapped" {
+				t.Fatalf("expected the stop signal to be sent first, got %q", lines.Text())
+			}
+			cmd.Wait()
+			took := time.Since(stopped)
+
+			signal := exitSignal(cmd.ProcessState)
+			if tt.killed {
+				if signal != "SIGKILL" {
+					t.Fatalf("expected the process to be killed, got %q", signal)
+				}
+				if took < stopTimeout {
+					t.Fatalf("expected SIGKILL only after %s, the process was gone after %s", stopTimeout, took)
+				}
+			} else {
+				if signal != "" || cmd.ProcessState.ExitCode() != 0 {
+					t.Fatalf("expected the process to exit on its own, got %q, exit %d", signal, cmd.ProcessState.ExitCode())
+				}
+				if took >= stopTimeout {
+					t.Fatalf("expected the process to exit before the stop timeout, took %s", took)
+				}
+			}
+		})
+	}
+}
+
+func TestStopProcessTreeKillsGroupMembersThatOutliveTheCommand(t *testing.T) {
+	const stopTimeout = 300 * time.Millisecond
+
+	// the command exits on SIGTERM, but leaves a child behind that ignores it
+	script := `sh -c 'trap "" TERM; echo up; while :; do sleep 0.05; done' & wait`
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := NewCommand(ctx, "sh", "-c", script)
+	cmd.stopTimeout = stopTimeout
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe returned error: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	pgid := cmd.Process.Pid
+	t.Cleanup(func() { syscall.Kill(-pgid, syscall.SIGKILL) })
+	lines := bufio.NewScanner(out)
+	if !lines.Scan() || lines.Text() != "up" {
+		t.Fatalf("expected the child to be started, got %q", lines.Text())
+	}
+
+	stopped := time.Now()
+	cancel()
+	cmd.Wait()
+	if err := syscall.Kill(-pgid, 0); err != nil {
+		t.Fatalf("expected the child to outlive the command, got %v", err)
+	}
+
+	for syscall.Kill(-pgid, 0) != syscall.ESRCH {
+		if time.Since(stopped) > 5*time.Second {
+			t.Fatal("expected the child to be killed after the stop timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if took := time.Since(stopped); took < stopTimeout {
+		t.Fatalf("expected SIGKILL only after %s, the group was gone after %s", stopTimeout, took)
+	}
+}
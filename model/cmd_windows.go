@@ -3,6 +3,7 @@
 package model
 
 import (
+	"os"
 	"os/exec"
 	"strconv"
 	"syscall"
@@ -14,10 +15,33 @@ import (
 // Not defined in the syscall package, so declared here.
 const createNoWindow = 0x08000000
 
+// exitSignal returns "", as processes on Windows are never terminated by a
+// signal.
+func exitSignal(state *os.ProcessState) string {
+	return ""
+}
+
 func (c *Cmd) setProcessGroup() {
 	c.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | createNoWindow}
 }
 
+func (c *Cmd) signalProcessTree(sig syscall.Signal) error {
+	if c.Process == nil {
+		return nil
+	}
+	if sig == syscall.SIGKILL {
+		return c.killProcessTree()
+	}
+
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(c.Process.Pid)).Run()
+}
+
+// processTreeAlive reports false, as the tree of a command that has exited
+// can't be found on Windows.
+func (c *Cmd) processTreeAlive() bool {
+	return false
+}
+
 func (c *Cmd) killProcessTree() error {
 	if c.Process == nil {
 		return nil
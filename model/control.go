@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steventhorne/sheepdog/control"
+)
+
+// defaultControlLogLines is how many log lines a logs request returns when it
+// doesn't ask for a number.
+const defaultControlLogLines = 100
+
+// handleControl answers a request that came in over the control socket.
+func (m *processList) handleControl(req control.Request) (control.Response, tea.Cmd) {
+	if req.Verb == control.VerbList {
+		res := control.Response{}
+		for _, p := range m.processes {
+			appendControlStatus(&res, p, 0, true)
+		}
+		return res, nil
+	}
+
+	if req.Name == "" {
+		return control.Response{Error: fmt.Sprintf("%s requires a process name", req.Verb)}, nil
+	}
+	p, ok := m.byName[req.Name]
+	if !ok {
+		return control.Response{Error: fmt.Sprintf("unknown process %q", req.Name)}, nil
+	}
+
+	switch req.Verb {
+	case control.VerbStatus:
+		res := control.Response{}
+		appendControlStatus(&res, p, 0, false)
+		return res, nil
+	case control.VerbStart:
+		return control.Response{}, p.Run()
+	case control.VerbStop:
+		return control.Response{}, p.Kill()
+	case control.VerbRestart:
+		return control.Response{}, p.Restart()
+	case control.VerbLogs:
+		if p.isGroup {
+			return control.Response{Error: fmt.Sprintf("%q is a group and has no logs", p.name)}, nil
+		}
+
+		n := req.Lines
+		if n <= 0 {
+			n = defaultControlLogLines
+		}
+		p.pullInbox()
+		entries := p.log.tail(n)
+
+		res := control.Response{Lines: make([]string, 0, len(entries))}
+		for _, e := range entries {
+			res.Lines = append(res.Lines, e.msg)
+		}
+		return res, nil
+	default:
+		return control.Response{Error: fmt.Sprintf("unknown command %q", req.Verb)}, nil
+	}
+}
+
+func appendControlStatus(res *control.Response, p *process, depth int, recursive bool) {
+	res.Processes = append(res.Processes, control.ProcessStatus{
+		Name:    p.name,
+		Status:  strings.TrimSpace(p.GetStatus().String()),
+		IsGroup: p.isGroup,
+		Depth:   depth,
+	})
+
+	if recursive {
+		for _, cp := range p.children {
+			appendControlStatus(res, cp, depth+1, true)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+//go:build !windows
+
+package model
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/control"
+)
+
+// controlServer is a control socket whose requests are answered by a process
+// list, the way the program does.
+type controlServer struct {
+	path string
+	pl   *processList
+	msgs chan control.Message
+}
+
+func newControlServer(t *testing.T, pl *processList) *controlServer {
+	t.Helper()
+	s := &controlServer{
+		path: filepath.Join(t.TempDir(), control.SocketName),
+		pl:   pl,
+		msgs: make(chan control.Message),
+	}
+	srv, err := control.Listen(s.path, func(msg control.Message) {
+		s.msgs <- msg
+	})
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return s
+}
+
+// do sends req over the socket and answers it on the calling goroutine.
+func (s *controlServer) do(req control.Request) (control.Response, error) {
+	type result struct {
+		res control.Response
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		res, err := control.Do(s.path, req)
+		done <- result{res, err}
+	}()
+
+	s.pl.Update(<-s.msgs)
+	r := <-done
+	return r.res, r.err
+}
+
+func TestControlRoundTrip(t *testing.T) {
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "api", Command: []string{"sh", "-c", "echo one; echo two; exec sleep 30"}, ReadyRegexp: "^two$"},
+		{Name: "jobs", GroupType: "parallel", Children: []config.ProcessConfig{
+			{Name: "worker", Command: []string{"sleep", "30"}},
+		}},
+	}})
+	t.Cleanup(func() {
+		for _, p := range pl.byName {
+			p.Cancel()
+		}
+	})
+	api := pl.byName["api"]
+	s := newControlServer(t, &pl)
+
+	res, err := s.do(control.Request{Verb: control.VerbList})
+	if err != nil {
+		t.Fatalf("list returned error: %v", err)
+	}
+	want := []control.ProcessStatus{
+		{Name: "api", Status: "idle"},
+		{Name: "jobs", Status: "idle", IsGroup: true},
+		{Name: "worker", Status: "idle", Depth: 1},
+	}
+	if !slices.Equal(res.Processes, want) {
+		t.Fatalf("unexpected list: %+v", res.Processes)
+	}
+
+	if _, err := s.do(control.Request{Verb: control.VerbStart, Name: "api"}); err != nil {
+		t.Fatalf("start returned error: %v", err)
+	}
+	waitForStatus(t, api, statusReady)
+
+	res, err = s.do(control.Request{Verb: control.VerbLogs, Name: "api", Lines: 1})
+	if err != nil {
+		t.Fatalf("logs returned error: %v", err)
+	}
+	if !slices.Equal(res.Lines, []string{"two"}) {
+		t.Fatalf("unexpected logs: %q", res.Lines)
+	}
+
+	if _, err := s.do(control.Request{Verb: control.VerbRestart, Name: "api"}); err != nil {
+		t.Fatalf("restart returned error: %v", err)
+	}
+	waitForStatus(t, api, statusReady)
+	if api.run.count != 2 {
+		t.Fatalf("expected api to run again, got run %d", api.run.count)
+	}
+
+	if _, err := s.do(control.Request{Verb: control.VerbStop, Name: "api"}); err != nil {
+		t.Fatalf("stop returned error: %v", err)
+	}
+	waitForStatus(t, api, statusExited)
+
+	res, err = s.do(control.Request{Verb: control.VerbStatus, Name: "api"})
+	if err != nil {
+		t.Fatalf("status returned error: %v", err)
+	}
+	if !slices.Equal(res.Processes, []control.ProcessStatus{{Name: "api", Status: "exited"}}) {
+		t.Fatalf("unexpected status: %+v", res.Processes)
+	}
+}
+
+func TestControlErrors(t *testing.T) {
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "api", Command: []string{"api"}},
+		{Name: "jobs", GroupType: "parallel", Children: []config.ProcessConfig{
+			{Name: "worker", Command: []string{"worker"}},
+		}},
+	}})
+	s := newControlServer(t, &pl)
+
+	tests := []struct {
+		req control.Request
+		err string
+	}{
+		{control.Request{Verb: control.VerbStart, Name: "nope"}, `unknown process "nope"`},
+		{control.Request{Verb: control.VerbLogs, Name: "nope"}, `unknown process "nope"`},
+		{control.Request{Verb: control.VerbStop}, "stop requires a process name"},
+		{control.Request{Verb: control.VerbLogs, Name: "jobs"}, `"jobs" is a group and has no logs`},
+		{control.Request{Verb: "frobnicate", Name: "api"}, `unknown command "frobnicate"`},
+	}
+	for _, tt := range tests {
+		_, err := s.do(tt.req)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%+v: expected error %q, got %v", tt.req, tt.err, err)
+		}
+	}
+}
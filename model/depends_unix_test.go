@@ -0,0 +1,109 @@
+//go:build !windows
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// newDependsList returns a list where api depends on db, both of which run
+// until they are stopped and only count as running, not ready.
+func newDependsList(t *testing.T) processList {
+	t.Helper()
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "db", Command: []string{"sleep", "30"}, ReadyRegexp: "^up$"},
+		{Name: "api", Command: []string{"sleep", "30"}, ReadyRegexp: "^up$", DependsOn: []string{"db"}},
+	}})
+	t.Cleanup(func() {
+		for _, p := range pl.byName {
+			p.Cancel()
+		}
+	})
+	return pl
+}
+
+func TestRunStartsDependenciesAndWaitsForThem(t *testing.T) {
+	pl := newDependsList(t)
+	db, api := pl.byName["db"], pl.byName["api"]
+
+	api.Run()
+	if db.status != statusRunning {
+		t.Fatalf("expected db to be started, got %v", db.status)
+	}
+	if api.status != statusWaiting {
+		t.Fatalf("expected api to wait for db, got %v", api.status)
+	}
+
+	// still waiting while db isn't ready
+	api.Update(processMsg{id: api.id})
+	if api.status != statusWaiting {
+		t.Fatalf("expected api to keep waiting, got %v", api.status)
+	}
+
+	db.status = statusReady
+	api.Update(processMsg{id: api.id})
+	if api.status != statusRunning {
+		t.Fatalf("expected api to start once db is ready, got %v", api.status)
+	}
+}
+
+func TestRunRestartsStoppedDependency(t *testing.T) {
+	pl := newDependsList(t)
+	db, api := pl.byName["db"], pl.byName["api"]
+	db.status = statusExited
+	db.stopRequested = true
+
+	api.Run()
+	if db.status != statusRunning {
+		t.Fatalf("expected the stopped db to be started again, got %v", db.status)
+	}
+	if api.status != statusWaiting {
+		t.Fatalf("expected api to wait for db, got %v", api.status)
+	}
+}
+
+func TestRunDoesNotRerunCompletedDependency(t *testing.T) {
+	pl := newDependsList(t)
+	db, api := pl.byName["db"], pl.byName["api"]
+	db.status = statusExited
+
+	api.Run()
+	if db.status != statusExited {
+		t.Fatalf("expected the completed db to be left alone, got %v", db.status)
+	}
+	if api.status != statusRunning {
+		t.Fatalf("expected api to start right away, got %v", api.status)
+	}
+}
+
+func TestWaitingProcessKeepsWaitingForStoppedDependency(t *testing.T) {
+	pl := newDependsList(t)
+	db, api := pl.byName["db"], pl.byName["api"]
+	api.status = statusWaiting
+	db.status = statusExited
+	db.stopRequested = true
+
+	api.Update(processMsg{id: api.id})
+	if api.status != statusWaiting {
+		t.Fatalf("expected api to keep waiting for the stopped db, got %v", api.status)
+	}
+}
+
+func TestDependencyErrorFailsWaitingProcess(t *testing.T) {
+	pl := newDependsList(t)
+	db, api := pl.byName["db"], pl.byName["api"]
+	api.status = statusWaiting
+	db.status = statusErrored
+
+	api.Update(processMsg{id: api.id})
+	if api.status != statusErrored {
+		t.Fatalf("expected api to error, got %v", api.status)
+	}
+	entries := api.log.entries()
+	if len(entries) == 0 || !strings.Contains(entries[len(entries)-1].msg, `dependency "db" errored`) {
+		t.Fatalf("expected the failed dependency to be logged, got %#v", entries)
+	}
+}
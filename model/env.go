@@ -0,0 +1,90 @@
+package model
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// envSource is one layer of a process's environment: either a dotenv file or
+// the env map from the config. Later sources override earlier ones, which is
+// how a child's settings win over the ones it inherits from its group.
+type envSource struct {
+	file string
+	vars map[string]string
+}
+
+// environ builds the environment for a new command by applying the process's
+// env sources on top of sheepdog's own environment.
+func (m *process) environ() ([]string, error) {
+	env := os.Environ()
+	index := make(map[string]int, len(env))
+	for i, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		index[k] = i
+	}
+
+	set := func(vars map[string]string) {
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			kv := k + "=" + vars[k]
+			if i, ok := index[k]; ok {
+				env[i] = kv
+			} else {
+				index[k] = len(env)
+				env = append(env, kv)
+			}
+		}
+	}
+
+	for _, src := range m.envSources {
+		if src.file == "" {
+			set(src.vars)
+			continue
+		}
+
+		path, err := m.resolvePath(src.file)
+		if err != nil {
+			return nil, err
+		}
+
+		vars, err := config.LoadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		set(vars)
+	}
+
+	// the active profile's overrides win, the innermost one last
+	var overrides []map[string]string
+	for p := m; p != nil; p = p.parent {
+		if p.override.Env != nil {
+			overrides = append(overrides, p.override.Env)
+		}
+	}
+	for i := len(overrides) - 1; i >= 0; i-- {
+		set(overrides[i])
+	}
+
+	return env, nil
+}
+
+// envView renders the environment the process was last started with.
+func (m *process) envView() string {
+	if m.env == nil {
+		return "process has not been started"
+	}
+
+	env := make([]string, len(m.env))
+	copy(env, m.env)
+	sort.Strings(env)
+
+	return strings.Join(env, "\n")
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"fmt"
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// ShutdownMsg asks a headless model to stop every process and exit. Sending
+// it a second time kills processes that are still inside their stop timeout.
+type ShutdownMsg struct{}
+
+// headlessStartedMsg is sent once the initial processes have been started so
+// the model can exit right away when there was nothing to run.
+type headlessStartedMsg struct{}
+
+// headlessModel runs processes without the TUI, streaming their output to a
+// writer with each line prefixed by the name of the process it came from.
+// It drives the same processList as the TUI, so groups, dependencies and
+// readiness behave identically.
+type headlessModel struct {
+	processes processList
+	output    io.Writer
+	stopping  bool
+	failed    bool
+}
+
+// NewHeadlessModel creates a model that runs the selected processes, or the
+// autorun ones when the selection is empty, and writes their output to w.
+func NewHeadlessModel(config config.Config, selection Selection, w io.Writer) (headlessModel, error) {
+	m := headlessModel{
+		processes: newProcessList(config),
+		output:    w,
+	}
+
+	if err := m.processes.useSelection(selection); err != nil {
+		return m, err
+	}
+
+	width := 0
+	for _, p := range m.processes.leaves() {
+		width = max(width, lipgloss.Width(p.name))
+	}
+	for _, p := range m.processes.leaves() {
+		p.output = w
+		p.outputPrefix = p.namePrefix(width)
+	}
+
+	return m, nil
+}
+
+// SetProgram hands the model the program it runs in, which must be done
+// before the program is run. Processes wake it up with their output and
+// status changes as they happen.
+func (m headlessModel) SetProgram(p *tea.Program) {
+	m.processes.setProgram(p)
+}
+
+// Failed reports whether a process errored while the model was running.
+func (m headlessModel) Failed() bool {
+	return m.failed
+}
+
+func (m headlessModel) Init() tea.Cmd {
+	started := func() tea.Msg {
+		return headlessStartedMsg{}
+	}
+
+	return tea.Batch(m.processes.Init(), started)
+}
+
+func (m headlessModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	cmds := make([]tea.Cmd, 0)
+
+	_, cmd := m.processes.Update(msg)
+	cmds = append(cmds, cmd)
+
+	if _, ok := msg.(ShutdownMsg); ok {
+		if m.stopping {
+			for _, p := range m.processes.leaves() {
+				p.Kill()
+			}
+		} else {
+			m.shutdown("shutting down")
+		}
+	}
+
+	if !m.stopping {
+		for _, p := range m.processes.leaves() {
+			// a process that watches its files gets another chance
+			// once they change
+			if p.status == statusErrored && p.restartAt.IsZero() && p.watchCh == nil {
+				m.failed = true
+				m.shutdown(fmt.Sprintf("%s errored, stopping all processes", p.name))
+				break
+			}
+		}
+	}
+
+	if m.processes.settled() {
+		cmds = append(cmds, tea.Quit)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m *headlessModel) shutdown(reason string) {
+	m.stopping = true
+	fmt.Fprintf(m.output, "sheepdog: %s\n", reason)
+	for _, p := range m.processes.processes {
+		p.Cancel()
+	}
+}
+
+func (m headlessModel) View() string {
+	return ""
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func TestProcessListSettled(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func(p *process)
+		settled bool
+	}{
+		{"exited", func(p *process) { p.status = statusExited }, true},
+		{"errored", func(p *process) { p.status = statusErrored }, true},
+		{"running", func(p *process) { p.status = statusRunning }, false},
+		{"stopping", func(p *process) { p.status = statusStopping }, false},
+		{"waiting", func(p *process) { p.status = statusWaiting }, false},
+		{"restart pending", func(p *process) {
+			p.status = statusErrored
+			p.restartAt = time.Now().Add(time.Second)
+		}, false},
+		{"watching for changes", func(p *process) {
+			p.status = statusExited
+			p.watchCh = make(chan string)
+		}, false},
+		{"stopped while watching", func(p *process) {
+			p.status = statusExited
+			p.watchCh = make(chan string)
+			p.stopRequested = true
+		}, true},
+		{"output not pulled in", func(p *process) {
+			p.status = statusExited
+			p.inboxCh <- logEntry{msg: "last line"}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+				{Name: "api", Command: []string{"api"}},
+				{Name: "worker", Command: []string{"worker"}},
+			}})
+			pl.byName["worker"].status = statusExited
+			tt.set(pl.byName["api"])
+
+			if got := pl.settled(); got != tt.settled {
+				t.Fatalf("settled = %v, want %v", got, tt.settled)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+//go:build !windows
+
+package model
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// syncBuffer is a bytes.Buffer that the processes and the test can share.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// runHeadlessModel runs the autorun processes of conf headless until the
+// model exits, calling during once the program is running. It returns the
+// final model and everything the processes wrote.
+func runHeadlessModel(t *testing.T, conf config.Config, during func(p *tea.Program, out *syncBuffer)) (headlessModel, string) {
+	t.Helper()
+	out := &syncBuffer{}
+	m, err := NewHeadlessModel(conf, Selection{}, out)
+	if err != nil {
+		t.Fatalf("NewHeadlessModel returned error: %v", err)
+	}
+	p := tea.NewProgram(m, tea.WithoutRenderer(), tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutSignalHandler())
+	m.SetProgram(p)
+
+	if during != nil {
+		go during(p, out)
+	}
+	timeout := time.AfterFunc(10*time.Second, p.Kill)
+	defer timeout.Stop()
+
+	final, err := p.Run()
+	if err != nil {
+		t.Fatalf("the program did not exit on its own: %v", err)
+	}
+	return final.(headlessModel), out.String()
+}
+
+// waitForOutput waits until out contains s.
+func waitForOutput(t *testing.T, out *syncBuffer, s string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), s) {
+		if time.Now().After(deadline) {
+			t.Errorf("timed out waiting for %q in %q", s, out.String())
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHeadlessExitsOnceEveryProcessSettled(t *testing.T) {
+	m, out := runHeadlessModel(t, config.Config{Processes: []config.ProcessConfig{
+		{Name: "build", Command: []string{"echo", "built"}, Autorun: true},
+		{Name: "test", Command: []string{"sh", "-c", "sleep 0.1; echo tested"}, Autorun: true},
+	}}, nil)
+
+	if m.Failed() {
+		t.Fatal("expected no failure")
+	}
+	for _, want := range []string{"built", "tested"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the output, got %q", want, out)
+		}
+	}
+}
+
+func TestHeadlessStopsEverythingWhenAProcessErrors(t *testing.T) {
+	m, out := runHeadlessModel(t, config.Config{Processes: []config.ProcessConfig{
+		{Name: "server", Command: []string{"sleep", "30"}, Autorun: true},
+		{Name: "broken", Command: []string{"sh", "-c", "exit 3"}, Autorun: true},
+	}}, nil)
+
+	if !m.Failed() {
+		t.Fatal("expected the run to fail")
+	}
+	if !strings.Contains(out, "sheepdog: broken errored, stopping all processes") {
+		t.Fatalf("expected the failure to be reported, got %q", out)
+	}
+	if s := m.processes.byName["server"].status; s != statusExited {
+		t.Fatalf("expected server to be stopped, got %v", s)
+	}
+}
+
+func TestHeadlessShutdown(t *testing.T) {
+	m, out := runHeadlessModel(t, config.Config{Processes: []config.ProcessConfig{
+		{Name: "server", Command: []string{"sh", "-c", "echo up; exec sleep 30"}, Autorun: true},
+	}}, func(p *tea.Program, out *syncBuffer) {
+		waitForOutput(t, out, "up")
+		p.Send(ShutdownMsg{})
+	})
+
+	if m.Failed() {
+		t.Fatal("expected a shutdown not to count as a failure")
+	}
+	if !strings.Contains(out, "sheepdog: shutting down") {
+		t.Fatalf("expected the shutdown to be reported, got %q", out)
+	}
+}
+
+func TestHeadlessSecondShutdownKills(t *testing.T) {
+	start := time.Now()
+	m, _ := runHeadlessModel(t, config.Config{Processes: []config.ProcessConfig{{
+		Name:        "server",
+		Command:     []string{"sh", "-c", "trap '' TERM; echo up; while :; do sleep 0.05; done"},
+		Autorun:     true,
+		StopTimeout: config.Duration(time.Minute),
+	}}}, func(p *tea.Program, out *syncBuffer) {
+		waitForOutput(t, out, "up")
+		p.Send(ShutdownMsg{})
+		p.Send(ShutdownMsg{})
+	})
+
+	if took := time.Since(start); took > 10*time.Second {
+		t.Fatalf("expected the second shutdown to skip the stop timeout, took %s", took)
+	}
+	if m.Failed() {
+		t.Fatal("expected a shutdown not to count as a failure")
+	}
+}
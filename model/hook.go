@@ -0,0 +1,198 @@
+package model
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// postStopTimeout is how long the postStop commands are given to finish
+// before they are stopped. They run once the process has been stopped, so
+// nothing else would ever end them.
+const postStopTimeout = time.Minute
+
+// preStartMsg reports that the preStart commands of a process are done. ctx
+// is the context of the start they ran for, so that one from an earlier
+// start can be told apart.
+type preStartMsg struct {
+	id  uuid.UUID
+	ctx context.Context
+	err error
+}
+
+// runPreStart runs the preStart commands in the background, reporting back
+// with a preStartMsg once they are done. The process counts as running in
+// the meantime so that it can be stopped.
+func (m *process) runPreStart() tea.Cmd {
+	dir, err := m.resolvePath(m.cwd)
+	if err != nil {
+		m.logEvent(logEntry{
+			msg:   err.Error(),
+			level: logError,
+		})
+		m.statusCh <- statusErrored
+		m.loadViewportFromInbox()
+		return nil
+	}
+
+	env, err := m.environ()
+	if err != nil {
+		m.logEvent(logEntry{
+			msg:   err.Error(),
+			level: logError,
+		})
+		m.statusCh <- statusErrored
+		m.loadViewportFromInbox()
+		return nil
+	}
+
+	// the command of the last run is gone, a second kill only has the
+	// hooks to stop
+	m.cmd = nil
+	m.status = statusRunning
+
+	id, ctx, hooks, in := m.id, m.ctx, m.preStart, m.inbox()
+	return func() tea.Msg {
+		err := runHooks(ctx, "preStart", hooks, dir, env, in)
+		return preStartMsg{id: id, ctx: ctx, err: err}
+	}
+}
+
+// finishPreStart launches the command once its preStart commands passed, or
+// fails the process if one of them didn't.
+func (m *process) finishPreStart(msg preStartMsg) tea.Cmd {
+	if msg.ctx != m.ctx {
+		return nil
+	}
+
+	switch {
+	case m.ctx.Err() != nil:
+		m.logEvent(logEntry{
+			msg:   "stopped before the command started",
+			level: logInfo,
+		})
+		m.status = statusExited
+	case msg.err != nil:
+		m.logEvent(logEntry{
+			msg:   msg.err.Error(),
+			level: logError,
+		})
+		m.status = statusErrored
+	default:
+		return m.exec()
+	}
+
+	m.loadViewportFromInbox()
+	return m.scheduleRestart()
+}
+
+// runHooks runs commands one after another in dir with env, stopping at the
+// first one that fails. Their output goes to in like the command's own, each
+// line marked with the kind of hook it came from.
+func runHooks(ctx context.Context, kind string, commands [][]string, dir string, env []string, in inbox) error {
+	for _, command := range commands {
+		in.send(logEntry{
+			msg:       fmt.Sprintf("[%s] $ %s", kind, strings.Join(command, " ")),
+			level:     logInfo,
+			timestamp: time.Now(),
+		})
+		if err := runHook(ctx, kind, command, dir, env, in); err != nil {
+			return fmt.Errorf("%s command %q failed: %w", kind, command, err)
+		}
+	}
+	return nil
+}
+
+// runHook runs a single hook command until it exits or ctx is done.
+func runHook(ctx context.Context, kind string, command []string, dir string, env []string, in inbox) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	name := command[0]
+	if strings.ContainsRune(name, '/') && !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	cmdPath, err := exec.LookPath(name)
+	if err != nil {
+		return err
+	}
+
+	cmd := NewCommand(ctx, cmdPath, command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	// our own pipes for the same reason as the command's, see start
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		return err
+	}
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+
+	err = cmd.Start()
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return err
+	}
+
+	var streams sync.WaitGroup
+	streams.Add(2)
+	go func() {
+		defer streams.Done()
+		defer stdout.Close()
+		streamHookOutput(stdout, in, kind, logInfo)
+	}()
+	go func() {
+		defer streams.Done()
+		defer stderr.Close()
+		streamHookOutput(stderr, in, kind, logError)
+	}()
+	streamsDone := make(chan struct{})
+	go func() {
+		streams.Wait()
+		close(streamsDone)
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-streamsDone:
+	case <-time.After(streamDrainTimeout):
+	}
+	return err
+}
+
+// streamHookOutput sends every line read from r to in, marked with the kind
+// of hook that wrote it.
+func streamHookOutput(r io.Reader, in inbox, kind string, level logLevel) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineBytes)
+	vt := &term{}
+	for scanner.Scan() {
+		line := vt.line(scanner.Text())
+		in.send(logEntry{
+			msg:       fmt.Sprintf("[%s] %s", kind, line),
+			level:     level,
+			timestamp: time.Now(),
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStreamHookOutputMarksLines(t *testing.T) {
+	ch := make(chan logEntry, 2)
+
+	streamHookOutput(strings.NewReader("migrating\ndone\n"), inbox{ch: ch}, "preStart", logError)
+	close(ch)
+
+	var msgs []string
+	for e := range ch {
+		if e.level != logError {
+			t.Fatalf("unexpected log level: %#v", e)
+		}
+		msgs = append(msgs, e.msg)
+	}
+	if strings.Join(msgs, "|") != "[preStart] migrating|[preStart] done" {
+		t.Fatalf("unexpected log lines: %q", msgs)
+	}
+}
+
+func TestRunHooksStopsAtFirstFailure(t *testing.T) {
+	ch := make(chan logEntry, 10)
+	hooks := [][]string{{"sheepdog-missing-hook"}, {"sheepdog-next-hook"}}
+
+	err := runHooks(context.Background(), "preStart", hooks, t.TempDir(), nil, inbox{ch: ch})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if !strings.HasPrefix(err.Error(), `preStart command ["sheepdog-missing-hook"] failed: `) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	close(ch)
+	for e := range ch {
+		if strings.Contains(e.msg, "sheepdog-next-hook") {
+			t.Fatalf("expected the hooks after the failing one not to run, got %q", e.msg)
+		}
+	}
+}
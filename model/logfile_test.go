@@ -0,0 +1,46 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "api.log")
+	lf, err := openLogFile(path, 100, 2)
+	if err != nil {
+		t.Fatalf("openLogFile returned error: %v", err)
+	}
+	defer lf.Close()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := lf.writeLine(now, "stdout", strings.Repeat("x", 20)); err != nil {
+			t.Fatalf("writeLine returned error: %v", err)
+		}
+	}
+
+	for _, name := range []string{"api.log", "api.log.1", "api.log.2"} {
+		info, err := os.Stat(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 100 {
+			t.Errorf("%s is %d bytes, larger than the max size", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated files to be kept")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "2024-01-02T03:04:05.000Z stdout | xxxx") {
+		t.Errorf("unexpected log line: %q", content)
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logView is the log of a process laid out for its viewport. Each line is
+// laid out once, after it is added, and let go of once it leaves memory, so
+// that a busy process only costs as much as its new lines.
+type logView struct {
+	width int
+	// first is the number of the first line in lines, counting every line
+	// added to the log.
+	first int
+	lines []string
+}
+
+// update lays out the lines added to log since the last update, starting
+// over when the width changed, and returns the laid out log with header
+// above it if there is one.
+func (v *logView) update(log *scrollback, width int, header string) string {
+	first := log.added - log.len()
+	if width != v.width || v.first+len(v.lines) < first {
+		v.width = width
+		v.first = first
+		v.lines = v.lines[:0]
+	}
+	if drop := first - v.first; drop > 0 {
+		v.lines = v.lines[drop:]
+		v.first = first
+	}
+
+	style := lipgloss.NewStyle().Width(width)
+	for _, entry := range log.since(v.first + len(v.lines)) {
+		v.lines = append(v.lines, style.Render(entry.msg))
+	}
+
+	sb := &strings.Builder{}
+	if header != "" {
+		sb.WriteString(style.Render(header))
+		sb.WriteString("\n")
+	}
+	for _, line := range v.lines {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	// the same empty last line the other views end with
+	sb.WriteString(style.Render(""))
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLogViewOnlyLaysOutNewLines(t *testing.T) {
+	log := newScrollback(3)
+	var v logView
+	log.add(logEntry{msg: "one"})
+	log.add(logEntry{msg: "two"})
+	v.update(&log, 10, "")
+
+	// a line that is laid out again would lose the marker
+	v.lines[1] = "kept"
+	log.add(logEntry{msg: "three"})
+	content := v.update(&log, 10, "")
+
+	lines := strings.Split(content, "\n")
+	if len(lines) != 4 || strings.TrimSpace(lines[1]) != "kept" || strings.TrimSpace(lines[2]) != "three" {
+		t.Fatalf("unexpected content: %q", content)
+	}
+	if len(lines[2]) != 10 {
+		t.Fatalf("expected the new line to be padded to the width, got %q", lines[2])
+	}
+}
+
+func TestLogViewDropsLinesThatLeaveMemory(t *testing.T) {
+	log := newScrollback(2)
+	t.Cleanup(func() {
+		if log.spill != nil {
+			log.spill.Close()
+		}
+	})
+	var v logView
+	for i := range 5 {
+		log.add(logEntry{msg: fmt.Sprint(i)})
+		v.update(&log, 20, "")
+	}
+
+	content := v.update(&log, 20, "--- older ---")
+	var got []string
+	for _, line := range strings.Split(content, "\n") {
+		got = append(got, strings.TrimSpace(line))
+	}
+	if strings.Join(got, "|") != "--- older ---|3|4|" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	if v.first != 3 || len(v.lines) != 2 {
+		t.Fatalf("expected lines 3 and 4 to be kept, got %d from %d", len(v.lines), v.first)
+	}
+}
+
+func TestLogViewStartsOverWhenTheWidthChanges(t *testing.T) {
+	log := newScrollback(3)
+	var v logView
+	log.add(logEntry{msg: "one two"})
+	v.update(&log, 10, "")
+
+	content := v.update(&log, 4, "")
+
+	if strings.Count(content, "\n") != 2 {
+		t.Fatalf("expected the line to be wrapped at the new width, got %q", content)
+	}
+}
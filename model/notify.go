@@ -0,0 +1,87 @@
+package model
+
+import (
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// wakeDelay is how long a process waits after something arrives on its
+// channels before it is woken up, so that the lines a command writes in a
+// burst are pulled in and laid out together.
+const wakeDelay = 16 * time.Millisecond
+
+// programRef is the program the model runs in. It is shared by every process
+// of the model and set once the program has been created.
+type programRef struct {
+	p atomic.Pointer[tea.Program]
+}
+
+// inbox is how the goroutines watching a command hand its lines to the
+// process.
+type inbox struct {
+	ch chan logEntry
+	// dropped counts the lines dropped because ch was full. It is nil for
+	// lossless processes, whose lines wait for room instead, which in turn
+	// makes the command wait.
+	dropped *atomic.Int64
+	// wake tells the process there is something to pull; nil for none.
+	wake func()
+}
+
+// send hands entry to the process, dropping it if the inbox is full unless
+// the process is lossless.
+func (in inbox) send(entry logEntry) {
+	if in.dropped == nil {
+		in.ch <- entry
+	} else {
+		select {
+		case in.ch <- entry:
+		default:
+			// Drop the log line if the buffer is full to avoid
+			// blocking the reader. This ensures the process stdout is
+			// continually drained even when the UI is busy.
+			in.dropped.Add(1)
+		}
+	}
+
+	if in.wake != nil {
+		in.wake()
+	}
+}
+
+// inbox returns the inbox of the process for the current run.
+func (m *process) inbox() inbox {
+	in := inbox{ch: m.inboxCh, wake: m.notify}
+	if !m.lossless {
+		in.dropped = &m.dropped
+	}
+	return in
+}
+
+// notify wakes the process up to pull in what arrived on its channels. It is
+// safe to call from any goroutine. Wakeups coalesce: until the process has
+// been woken, further ones are dropped, so a chatty command sends the program
+// a message per batch of lines rather than one per line.
+func (m *process) notify() {
+	if m.program == nil {
+		return
+	}
+	p := m.program.p.Load()
+	if p == nil || m.woken.Swap(true) {
+		return
+	}
+
+	id := m.id
+	go func() {
+		time.Sleep(wakeDelay)
+		p.Send(processMsg{id: id})
+	}()
+}
+
+// hasPending reports whether anything is waiting on the channels of the
+// process.
+func (m *process) hasPending() bool {
+	return len(m.inboxCh) > 0 || len(m.statusCh) > 0 || len(m.exitCh) > 0
+}
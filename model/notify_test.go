@@ -0,0 +1,86 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// wakeRecorder is a model that passes on the wakeups it gets.
+type wakeRecorder struct {
+	woken chan processMsg
+}
+
+func (r wakeRecorder) Init() tea.Cmd { return nil }
+
+func (r wakeRecorder) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(processMsg); ok {
+		r.woken <- msg
+	}
+	return r, nil
+}
+
+func (r wakeRecorder) View() string { return "" }
+
+func TestNotifyCoalescesWakeups(t *testing.T) {
+	r := wakeRecorder{woken: make(chan processMsg, 10)}
+	program := tea.NewProgram(r, tea.WithInput(nil), tea.WithoutRenderer(), tea.WithoutSignalHandler())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = program.Run()
+	}()
+	t.Cleanup(func() {
+		program.Quit()
+		<-done
+	})
+
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "api", Command: []string{"true"}},
+	}})
+	pl.setProgram(program)
+	p := pl.byName["api"]
+
+	in := p.inbox()
+	for range 3 {
+		in.send(logEntry{msg: "line"})
+	}
+
+	select {
+	case msg := <-r.woken:
+		if msg.id != p.id {
+			t.Fatalf("expected a wakeup for the process, got %#v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a wakeup")
+	}
+	select {
+	case msg := <-r.woken:
+		t.Fatalf("expected the wakeups to be coalesced, got another %#v", msg)
+	case <-time.After(10 * wakeDelay):
+	}
+
+	// once woken up, the next line wakes it up again
+	p.woken.Store(false)
+	in.send(logEntry{msg: "line"})
+	select {
+	case <-r.woken:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the second wakeup")
+	}
+}
+
+func TestNotifyWithoutProgram(t *testing.T) {
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "api", Command: []string{"true"}},
+	}})
+	p := pl.byName["api"]
+
+	p.notify()
+
+	if p.woken.Load() {
+		t.Fatal("expected no wakeup to be pending without a program")
+	}
+}
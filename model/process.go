@@ -2,6 +2,7 @@ package model
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"fmt"
 	"io"
@@ -9,12 +10,18 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/google/uuid"
 	"github.com/steventhorne/sheepdog/config"
 	"github.com/steventhorne/sheepdog/style"
@@ -26,27 +33,42 @@ const (
 	statusIdle processStatus = iota
 	statusExited
 	statusReady
+	statusWaiting
 	statusRunning
+	statusUnhealthy
+	statusStopping
 	statusErrored
 )
 
 func (s processStatus) String() string {
 	switch s {
 	case statusIdle:
-		return "idle    "
+		return "idle     "
 	case statusExited:
-		return "exited  "
+		return "exited   "
 	case statusReady:
-		return "ready   "
+		return "ready    "
+	case statusWaiting:
+		return "waiting  "
 	case statusRunning:
-		return "running "
+		return "running  "
+	case statusUnhealthy:
+		return "unhealthy"
+	case statusStopping:
+		return "stopping "
 	case statusErrored:
-		return "error   "
+		return "error    "
 	default:
-		return "null    "
+		return "null     "
 	}
 }
 
+// isActive reports whether a process in this status still has a live
+// command attached to it.
+func (s processStatus) isActive() bool {
+	return s == statusRunning || s == statusReady || s == statusUnhealthy || s == statusStopping
+}
+
 type logLevel string
 
 const (
@@ -55,59 +77,204 @@ const (
 )
 
 // logBufferSize is the number of log lines buffered per process before new
-// lines are dropped to keep the reader from blocking.
+// lines are dropped to keep the reader from blocking, or, for lossless
+// processes, before the reader waits. It is also the most lines pulled from
+// the buffer at once, so that a command that is never short of output can't
+// hold up the UI.
 const logBufferSize = 1024
 
-// maxLogLines is the number of log lines retained in memory per process;
-// older lines are discarded as new ones arrive.
-const maxLogLines = 1024
-
 // maxLogLineBytes is the maximum length of a single log line the scanner
 // will accept before reporting an error.
 const maxLogLineBytes = 1024 * 1024
 
+// streamDrainTimeout is how long to wait for the rest of a process's output
+// after it exits before reporting the exit. Children it left running may keep
+// writing to the pipes, and their output keeps streaming in afterwards.
+const streamDrainTimeout = time.Second
+
+// defaultStopTimeout is how long a process is given to exit after receiving
+// its stop signal before it is killed.
+const defaultStopTimeout = 5 * time.Second
+
+// defaultPtyCols and defaultPtyRows size the pseudo-terminal of a process
+// started before the size of the window is known.
+const (
+	defaultPtyCols = 80
+	defaultPtyRows = 24
+)
+
+const (
+	restartNever     = "never"
+	restartOnFailure = "on-failure"
+	restartAlways    = "always"
+)
+
+// defaultRestartBackoff and defaultRestartMaxBackoff bound the delay between
+// automatic restarts. The delay doubles after every restart.
+const (
+	defaultRestartBackoff    = time.Second
+	defaultRestartMaxBackoff = 30 * time.Second
+)
+
+// restartResetAfter is how long a run has to stay up for the restarts before
+// it to be forgotten, so that the next crash is restarted after the initial
+// backoff and counts as the first restart again.
+const restartResetAfter = time.Minute
+
 type logEntry struct {
 	msg   string
 	level logLevel
+	// timestamp is when the line was read, or when it reached the process
+	// for lines sheepdog reports itself. Merged views are ordered by it.
+	timestamp time.Time
+}
+
+// output is a stream of a command's output and the level its lines are
+// logged at.
+type output struct {
+	r     io.ReadCloser
+	level logLevel
 }
 
+// processMsg wakes a process up to pull in its output and status changes.
 type processMsg struct {
 	id uuid.UUID
 }
 
+// processTick wakes a process up a little later. A process waiting on its
+// dependencies is woken up this way until they are ready, as nothing else
+// would tell it.
 func processTick(id uuid.UUID) tea.Cmd {
 	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
 		return processMsg{id: id}
 	})
 }
 
-type process struct {
-	id          uuid.UUID
-	name        string
-	command     []string
-	autorun     bool
-	cwd         string
-	readyRegexp *regexp.Regexp
+type restartMsg struct {
+	id  uuid.UUID
+	gen int
+}
+
+// restartTick wakes the process up once a second while it waits to be
+// restarted so the countdown in the list stays current.
+func restartTick(id uuid.UUID, gen int, wait time.Duration) tea.Cmd {
+	return tea.Tick(min(wait, time.Second), func(t time.Time) tea.Msg {
+		return restartMsg{id: id, gen: gen}
+	})
+}
 
+type process struct {
+	id      uuid.UUID
+	name    string
+	command []string
+	autorun bool
+	cwd     string
+	baseDir string
+	// override is how the active profile changes the way the process runs.
+	override      config.Override
+	logFile       string
+	logMaxSize    int64
+	logMaxFiles   int
+	envSources    []envSource
+	env           []string
+	readyRegexp   *regexp.Regexp
+	readyOnPort   bool
+	readyCheck    *check
+	livenessCheck *check
+	stopSignal    syscall.Signal
+	stopTimeout   time.Duration
+	// preStart and postStop are commands run before the command starts and
+	// after it exits.
+	preStart [][]string
+	postStop [][]string
+
+	restartPolicy     string
+	maxRestarts       int
+	restartBackoff    time.Duration
+	restartMaxBackoff time.Duration
+	restartCount      int
+	restartAt         time.Time
+	restartGen        int
+	stopRequested     bool
+	restartAfterStop  bool
+
+	dependsOnNames []string
+	dependsOn      []*process
+
+	watch   *watcher
+	watchCh chan string
+
+	// isAll marks the virtual entry at the top of the list whose children
+	// are every top-level process.
+	isAll             bool
 	isGroup           bool
 	groupType         string
+	parent            *process
 	children          []*process
 	startupChildIndex int
 
 	ctx    context.Context
-	cancel context.CancelFunc
+	cancel context.CancelCauseFunc
+	cmd    *Cmd
+	dir    string
+	// terminal is the pseudo-terminal the command runs in when pty is set.
+	// It is closed once all of its output has been read.
+	pty      bool
+	terminal *os.File
+	// stdin is where input sent to the command is written: the write end
+	// of its stdin pipe, or its terminal.
+	stdin *os.File
+
+	// runCtx lives as long as the current run of the command and stops
+	// the liveness check once it exits.
+	runCtx          context.Context
+	livenessStarted bool
 
 	status processStatus
-	log    []logEntry
+	log    scrollback
+	// dropped counts the lines dropped since the last pull because the
+	// inbox was full. Lossless processes wait for room instead.
+	dropped  atomic.Int64
+	lossless bool
 
 	inboxCh  chan logEntry
 	statusCh chan processStatus
+	exitCh   chan exitInfo
+	// program is what the process is woken up through when something
+	// arrives on its channels. woken is set while a wakeup is on its way.
+	program *programRef
+	woken   atomic.Bool
+
+	// run describes the current or last run of the command.
+	run runInfo
+	// usage is the CPU and memory the command is using.
+	usage processUsage
+
+	// color tells the process apart from others where their output is
+	// shown together.
+	color lipgloss.Color
+
+	// output receives every log line, prefixed with outputPrefix, when
+	// running headless.
+	output       io.Writer
+	outputPrefix string
 
 	isSelected   bool
 	isFocused    bool
 	isReady      bool
 	viewport     viewport.Model
 	showViewport bool
+	showEnv      bool
+	// showHistory adds the lines spilled to disk to the viewport.
+	showHistory bool
+
+	// logView is the log as laid out in the viewport.
+	logView logView
+
+	search logSearch
+	// prompt is the search or filter being typed for this process, shown
+	// in place of the search summary.
+	prompt string
 }
 
 func (m *process) IsFocused() bool {
@@ -122,7 +289,19 @@ func (m *process) Cancel() {
 	}
 
 	if m.cancel != nil {
-		m.cancel()
+		m.cancel(nil)
+	}
+
+	m.stopRequested = true
+	m.restartAfterStop = false
+	m.restartAt = time.Time{}
+
+	if m.status == statusWaiting {
+		m.status = statusIdle
+	}
+
+	if m.status.isActive() {
+		m.status = statusStopping
 	}
 }
 
@@ -137,20 +316,81 @@ func newProcess(config config.ProcessConfig) *process {
 		command:     config.Command,
 		autorun:     config.Autorun,
 		cwd:         config.Cwd,
+		logFile:     config.LogFile,
+		logMaxSize:  defaultLogMaxSize,
+		logMaxFiles: defaultLogMaxFiles,
 		readyRegexp: nil,
-		isGroup:     len(config.Children) > 0,
-		groupType:   config.GroupType,
-		children:    make([]*process, 0, len(config.Children)),
-		status:      statusIdle,
-		inboxCh:     make(chan logEntry, logBufferSize),
-		statusCh:    make(chan processStatus, 10),
-		log:         make([]logEntry, 0, 100),
+		stopSignal:  syscall.SIGTERM,
+		stopTimeout: defaultStopTimeout,
+		preStart:    config.PreStart,
+		postStop:    config.PostStop,
+
+		restartPolicy:     restartNever,
+		maxRestarts:       config.MaxRestarts,
+		restartBackoff:    defaultRestartBackoff,
+		restartMaxBackoff: defaultRestartMaxBackoff,
+
+		dependsOnNames: config.DependsOn,
+
+		isGroup:   len(config.Children) > 0,
+		groupType: config.GroupType,
+		children:  make([]*process, 0, len(config.Children)),
+		status:    statusIdle,
+		inboxCh:   make(chan logEntry, logBufferSize),
+		statusCh:  make(chan processStatus, 10),
+		exitCh:    make(chan exitInfo, 1),
+		log:       newScrollback(cmp.Or(config.Scrollback, defaultScrollback)),
+		lossless:  config.Lossless,
+		search:    logSearch{current: -1},
+	}
+
+	if config.ReadyCheck != nil {
+		c, err := newCheck(config.ReadyCheck)
+		if err != nil {
+			p.log.add(logEntry{
+				msg:   fmt.Sprintf("process %s has an invalid ready check: %v", p.name, err),
+				level: logError,
+			})
+		} else {
+			p.readyCheck = c
+		}
+	}
+
+	if config.LivenessCheck != nil {
+		c, err := newCheck(config.LivenessCheck)
+		if err != nil {
+			p.log.add(logEntry{
+				msg:   fmt.Sprintf("process %s has an invalid liveness check: %v", p.name, err),
+				level: logError,
+			})
+		} else {
+			p.livenessCheck = c
+		}
+	}
+
+	if config.Watch != nil {
+		w, err := newWatcher(config.Watch)
+		if err != nil {
+			p.log.add(logEntry{
+				msg:   fmt.Sprintf("process %s has an invalid watch: %v", p.name, err),
+				level: logError,
+			})
+		} else {
+			p.watch = w
+		}
+	}
+
+	for _, f := range config.EnvFile {
+		p.envSources = append(p.envSources, envSource{file: f})
+	}
+	if len(config.Env) > 0 {
+		p.envSources = append(p.envSources, envSource{vars: config.Env})
 	}
 
 	if config.ReadyRegexp != "" {
 		rg, err := regexp.Compile(config.ReadyRegexp)
 		if err != nil {
-			p.log = append(p.log, logEntry{
+			p.log.add(logEntry{
 				msg:   fmt.Sprintf("process %s has an invalid ready regexp", p.name),
 				level: logError,
 			})
@@ -159,28 +399,76 @@ func newProcess(config config.ProcessConfig) *process {
 		}
 	}
 
-	return p
-}
+	if config.ReadyOnPort {
+		if usageSupported {
+			p.readyOnPort = true
+		} else {
+			p.log.add(logEntry{
+				msg:   fmt.Sprintf("process %s: readyOnPort is only supported on Linux and is ignored", p.name),
+				level: logError,
+			})
+		}
+	}
 
-func (m *process) Init() tea.Cmd {
-	if m.autorun {
-		return m.Run()
+	if config.Pty {
+		if ptySupported {
+			p.pty = true
+		} else {
+			p.log.add(logEntry{
+				msg:   fmt.Sprintf("process %s: pty is not supported on Windows and is ignored", p.name),
+				level: logError,
+			})
+		}
 	}
 
-	if m.isGroup {
-		cmds := make([]tea.Cmd, 0, len(m.children))
-		for _, cp := range m.children {
-			cmds = append(cmds, cp.Init())
+	if config.StopSignal != "" {
+		if sig, ok := lookupStopSignal(config.StopSignal); ok {
+			p.stopSignal = sig
+		} else {
+			p.log.add(logEntry{
+				msg:   fmt.Sprintf("process %s has an invalid stop signal %q", p.name, config.StopSignal),
+				level: logError,
+			})
 		}
-		return tea.Batch(cmds...)
 	}
 
-	return nil
+	if config.StopTimeout > 0 {
+		p.stopTimeout = time.Duration(config.StopTimeout)
+	}
+
+	switch config.Restart {
+	case "", restartNever:
+	case restartOnFailure, restartAlways:
+		p.restartPolicy = config.Restart
+	default:
+		p.log.add(logEntry{
+			msg:   fmt.Sprintf("process %s has an invalid restart policy %q", p.name, config.Restart),
+			level: logError,
+		})
+	}
+
+	if config.RestartBackoff > 0 {
+		p.restartBackoff = time.Duration(config.RestartBackoff)
+	}
+	if config.RestartMaxBackoff > 0 {
+		p.restartMaxBackoff = time.Duration(config.RestartMaxBackoff)
+	}
+
+	return p
+}
+
+// Init starts the process if it is set to autorun, or else its autorun
+// children.
+func (m *process) Init() tea.Cmd {
+	return Selection{}.run(m)
 }
 
 func (m *process) GetStatus() processStatus {
 	if m.isGroup {
 		s := statusIdle
+		if m.status == statusWaiting {
+			s = statusWaiting
+		}
 		for _, cp := range m.children {
 			cs := cp.GetStatus()
 			if cs > s {
@@ -193,54 +481,228 @@ func (m *process) GetStatus() processStatus {
 }
 
 func (m *process) pullInbox() {
-	for {
+pull:
+	for range logBufferSize {
 		select {
 		case entry := <-m.inboxCh:
-			m.log = append(m.log, entry)
-		default:
-			if len(m.log) > maxLogLines {
-				trimmed := make([]logEntry, maxLogLines)
-				copy(trimmed, m.log[len(m.log)-maxLogLines:])
-				m.log = trimmed
+			if entry.timestamp.IsZero() {
+				entry.timestamp = time.Now()
 			}
-			return
+			m.addLog(entry)
+		default:
+			break pull
 		}
 	}
+
+	if n := m.dropped.Swap(0); n > 0 {
+		m.addLog(logEntry{
+			msg:       fmt.Sprintf("--- %d lines dropped ---", n),
+			level:     logError,
+			timestamp: time.Now(),
+		})
+	}
+}
+
+// logEvent adds a line sheepdog reports itself to the log, after whatever
+// output has already arrived, and shows it. It is for the UI goroutine, which is the one
+// that drains the inbox: sending to it there would block for good once the
+// inbox is full, as it stays for a busy lossless process.
+func (m *process) logEvent(entry logEntry) {
+	m.pullInbox()
+	if entry.timestamp.IsZero() {
+		entry.timestamp = time.Now()
+	}
+	m.addLog(entry)
+	m.renderLog()
+}
+
+// addLog adds entry to the log, and writes it out when running headless.
+func (m *process) addLog(entry logEntry) {
+	m.log.add(entry)
+	if m.output != nil {
+		fmt.Fprintf(m.output, "%s %s\n", m.outputPrefix, entry.msg)
+	}
 }
 
 func (m *process) pullStatus() {
 	for {
 		select {
+		case exit := <-m.exitCh:
+			m.run.ended = exit.ended
+			m.run.exitCode = exit.exitCode
+			m.run.signal = exit.signal
 		case status := <-m.statusCh:
+			switch status {
+			case statusRunning, statusReady, statusUnhealthy:
+				// readiness and liveness reports can arrive after the
+				// process was told to stop, or has already exited, and
+				// must not make it look alive again
+				if !m.status.isActive() || m.status == statusStopping {
+					continue
+				}
+				if status == statusUnhealthy && m.status != statusReady {
+					continue
+				}
+			case statusStopping:
+				if !m.status.isActive() {
+					continue
+				}
+			}
 			m.status = status
+			if status == statusReady {
+				m.startLivenessCheck()
+			}
 		default:
 			return
 		}
 	}
 }
 
+// startLivenessCheck begins probing the process once it first becomes ready
+// during a run.
+func (m *process) startLivenessCheck() {
+	if m.livenessCheck == nil || m.livenessStarted || m.runCtx == nil {
+		return
+	}
+	m.livenessStarted = true
+	go m.livenessCheck.watchLiveness(m.runCtx, m.dir, m.env, m.inboxCh, m.statusCh, m.notify)
+}
+
 func (m *process) loadViewportFromInbox() {
 	m.pullInbox()
 	m.pullStatus()
+	m.renderLog()
+}
 
-	sb := &strings.Builder{}
-	for _, line := range m.log {
-		sb.WriteString(line.msg)
-		sb.WriteString("\n")
+// renderLog lays the log out in the viewport. Only the selected process is on
+// screen, so the others are left alone until they are selected.
+func (m *process) renderLog() {
+	if !m.isSelected {
+		return
 	}
 
 	atBottom := m.viewport.AtBottom()
-	m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(sb.String()))
+	switch {
+	case m.showEnv:
+		m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(m.envView()))
+	case !m.isGroup && !m.showHistory && !m.search.active():
+		// the log as it comes in, which only needs its new lines laid out
+		m.viewport.SetContent(m.logView.update(&m.log, m.viewport.Width, m.summaryLine().msg))
+	default:
+		entries := m.log.entries()
+		if m.isGroup {
+			entries = m.mergedLog()
+		} else if m.showHistory {
+			history, err := m.log.loadHistory()
+			if err != nil {
+				history = append(history, logEntry{
+					msg:   fmt.Sprintf("--- failed to read older lines: %v ---", err),
+					level: logError,
+				})
+			}
+			entries = slices.Concat(history, entries)
+		} else if summary := m.summaryLine(); summary.msg != "" {
+			entries = append([]logEntry{summary}, entries...)
+		}
+
+		if m.search.active() {
+			// the search lays out each line itself so that it knows
+			// which viewport line every match ends up on
+			m.viewport.SetContent(m.search.render(entries, m.viewport.Width))
+		} else {
+			sb := &strings.Builder{}
+			for _, line := range entries {
+				sb.WriteString(line.msg)
+				sb.WriteString("\n")
+			}
+			m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(sb.String()))
+		}
+	}
 	if atBottom {
 		m.viewport.GotoBottom()
 	}
 }
 
+// summaryLine tells about the lines of the log that aren't in memory, or has
+// no message when there are none.
+func (m *process) summaryLine() logEntry {
+	summary := m.log.summary()
+	if summary == "" {
+		return logEntry{}
+	}
+	return logEntry{
+		msg:   fmt.Sprintf("--- %s, press h to show them ---", summary),
+		level: logInfo,
+	}
+}
+
+// mergedLog interleaves the logs of every process in a group, and the group's
+// own, in the order their lines arrived. Each line is prefixed with the name
+// of the process it came from. Lines spilled to disk are left out.
+func (m *process) mergedLog() []logEntry {
+	sources := []*process{m}
+	var walk func(ps []*process)
+	walk = func(ps []*process) {
+		for _, p := range ps {
+			sources = append(sources, p)
+			walk(p.children)
+		}
+	}
+	walk(m.children)
+
+	width := 0
+	total := 0
+	for _, p := range sources {
+		width = max(width, lipgloss.Width(p.name))
+		total += p.log.len()
+	}
+
+	merged := make([]logEntry, 0, total)
+	for _, p := range sources {
+		prefix := p.namePrefix(width)
+		for _, entry := range p.log.entries() {
+			entry.msg = prefix + " " + entry.msg
+			merged = append(merged, entry)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].timestamp.Before(merged[j].timestamp)
+	})
+
+	return merged
+}
+
+// namePrefix is the process's name in brackets, in its color and padded to
+// width, used to tell its lines apart from those of other processes.
+func (m *process) namePrefix(width int) string {
+	padding := strings.Repeat(" ", max(0, width-lipgloss.Width(m.name)))
+	return lipgloss.NewStyle().Foreground(m.color).Render(fmt.Sprintf("[%s]%s", m.name, padding))
+}
+
+// jumpToMatch scrolls the viewport to the next or previous search match.
+func (m *process) jumpToMatch(forward bool) {
+	var line int
+	if forward {
+		line = m.search.next(m.viewport.YOffset)
+	} else {
+		line = m.search.prev(m.viewport.YOffset)
+	}
+	if line >= 0 {
+		m.viewport.SetYOffset(line)
+	}
+}
+
+// searchStatus is the prompt being typed, or else a summary of the search
+// and filter applied to the viewport.
+func (m *process) searchStatus() string {
+	if m.prompt != "" {
+		return m.prompt
+	}
+	return m.search.String()
+}
+
 func (m *process) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var (
-		cmd  tea.Cmd
-		cmds []tea.Cmd
-	)
+	var cmds []tea.Cmd
 
 	if m.isGroup {
 		for _, cp := range m.children {
@@ -248,6 +710,12 @@ func (m *process) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
+		// the merged log only changes when one of the children pulls in
+		// new lines
+		if _, ok := msg.(processMsg); ok && m.isSelected {
+			m.loadViewportFromInbox()
+		}
+
 		if m.groupType == "sequential" && m.startupChildIndex < len(m.children) {
 			cp := m.children[m.startupChildIndex]
 			switch cp.GetStatus() {
@@ -257,32 +725,89 @@ func (m *process) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					cmds = append(cmds, m.children[m.startupChildIndex].Run())
 				}
 			case statusErrored:
-				entry := logEntry{
+				cp.logEvent(logEntry{
 					msg:   fmt.Sprintf("sequential group %q halted: %q errored before becoming ready", m.name, cp.name),
 					level: logError,
-				}
-				select {
-				case cp.inboxCh <- entry:
-				default:
-				}
+				})
 				m.startupChildIndex = len(m.children)
 			}
 		}
 	}
 
+	cmds = append(cmds, m.runAgainIfStopped())
+
 	switch msg := msg.(type) {
 	case processMsg:
 		if msg.id != m.id {
 			return m, tea.Batch(cmds...)
 		}
+		// cleared before pulling, so that whatever arrives from here on
+		// wakes the process up again
+		m.woken.Store(false)
+
+		if m.status == statusWaiting {
+			cmd := m.checkDependencies()
+			if m.status == statusWaiting {
+				cmd = processTick(m.id)
+			}
+			if cmd != nil {
+				return m, tea.Batch(append(cmds, cmd)...)
+			}
+		}
 
+		wasActive := m.status.isActive()
 		m.loadViewportFromInbox()
 
-		if len(m.inboxCh) > 0 || len(m.statusCh) > 0 || m.status == statusRunning || m.status == statusReady {
-			return m, processTick(m.id)
+		if wasActive && !m.status.isActive() {
+			if m.run.ended.Sub(m.run.started) >= restartResetAfter {
+				m.restartCount = 0
+			}
+			cmds = append(cmds, m.scheduleRestart(), m.runAgainIfStopped())
 		}
 		return m, tea.Batch(cmds...)
-	case tea.WindowSizeMsg:
+	case restartMsg:
+		if msg.id != m.id || msg.gen != m.restartGen || m.restartAt.IsZero() {
+			return m, tea.Batch(cmds...)
+		}
+
+		if wait := time.Until(m.restartAt); wait > 0 {
+			return m, tea.Batch(append(cmds, restartTick(m.id, m.restartGen, wait))...)
+		}
+
+		m.restartAt = time.Time{}
+		return m, tea.Batch(append(cmds, m.start())...)
+	case preStartMsg:
+		if msg.id != m.id {
+			return m, tea.Batch(cmds...)
+		}
+
+		return m, tea.Batch(append(cmds, m.finishPreStart(msg))...)
+	case watchMsg:
+		if msg.id != m.id {
+			return m, tea.Batch(cmds...)
+		}
+
+		return m, tea.Batch(append(cmds, m.onChange(msg.file), waitForChange(m.id, m.watchCh))...)
+	}
+
+	cmds = append(cmds, m.updateViewport(msg))
+	return m, tea.Batch(cmds...)
+}
+
+// runAgainIfStopped runs the process again once it stopped, if a change to
+// its files asked for it while it was running.
+func (m *process) runAgainIfStopped() tea.Cmd {
+	if !m.restartAfterStop || m.GetStatus().isActive() {
+		return nil
+	}
+	m.restartAfterStop = false
+	return m.Run()
+}
+
+// updateViewport sizes the viewport to the window and passes msg on to it
+// while the process is selected.
+func (m *process) updateViewport(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
 		if !m.isReady {
 			// Since this program is using the full size of the viewport we
 			// need to wait until we've received the window dimensions before
@@ -297,40 +822,159 @@ func (m *process) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Width = msg.Width + style.WidthViewportOffset
 			m.viewport.Height = msg.Height + style.HeightViewportOffset
 		}
+
+		if m.terminal != nil {
+			// fails once the command is gone and the terminal closed,
+			// which is fine
+			_ = resizePty(m.terminal, m.viewport.Width, m.viewport.Height)
+		}
+		m.renderLog()
 	}
 
-	if m.isSelected {
-		m.viewport, cmd = m.viewport.Update(msg)
-		cmds = append(cmds, cmd)
+	if !m.isSelected {
+		return nil
 	}
 
-	return m, tea.Batch(cmds...)
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return cmd
 }
 
 func (m *process) View() string {
+	var suffix string
+	if m.showEnv && !m.isGroup {
+		suffix += "  [env]"
+	}
+	if status := m.searchStatus(); status != "" {
+		suffix += "  " + status
+	}
+
+	title := fmt.Sprintf("%s ##  %s", m.GetStatus(), m.name)
+	var details string
 	if m.isGroup {
-		return style.StyleDetails.Render(lipgloss.JoinVertical(lipgloss.Center, style.StyleDetailsHeader.Width(m.viewport.Width).Render(fmt.Sprintf("%s ##  %s", m.GetStatus(), m.name)), m.FocusedView()))
+		active, leaves := 0, leavesOf(m)
+		for _, l := range leaves {
+			if l.status.isActive() {
+				active++
+			}
+		}
+		details = fmt.Sprintf("%d of %d running", active, len(leaves))
+		if usage := m.usage.details(); usage != "" {
+			details += " · " + usage
+		}
 	} else {
-		return style.StyleDetails.Render(lipgloss.JoinVertical(lipgloss.Center, style.StyleDetailsHeader.Width(m.viewport.Width).Render(fmt.Sprintf("%s ##  %s", m.GetStatus(), strings.Join(m.command, " "))), m.FocusedView()))
+		prefix := fmt.Sprintf("%s ##  ", m.GetStatus())
+		// a header that wrapped would push the log out of place, so a
+		// long command is cut short
+		room := max(1, m.viewport.Width-lipgloss.Width(prefix)-lipgloss.Width(suffix))
+		title = prefix + ansi.Truncate(strings.Join(m.commandLine(), " "), room, "…")
+
+		details = m.run.details(time.Now())
+		if details == "" {
+			details = "not started"
+		}
+		if usage := m.usage.details(); usage != "" && m.status.isActive() {
+			details += " · " + usage
+		}
 	}
+
+	header := lipgloss.JoinVertical(lipgloss.Center, title+suffix, ansi.Truncate(details, m.viewport.Width, "…"))
+	return style.StyleDetails.Render(lipgloss.JoinVertical(lipgloss.Center, style.StyleDetailsHeader.Width(m.viewport.Width).Render(header), m.FocusedView()))
 }
 
 func (m *process) FocusedView() string {
-	if m.isGroup {
+	// a focused group is only expanded in the list, so its view never
+	// takes over the screen
+	if status := m.searchStatus(); status != "" && m.isFocused && !m.isGroup {
+		return lipgloss.JoinVertical(lipgloss.Left, m.viewport.View(), status)
+	}
+	return m.viewport.View()
+}
 
-		var sb strings.Builder
-		for _, cp := range m.children {
-			fmt.Fprintf(&sb, "%s ##  %s\n", cp.GetStatus(), cp.name)
+func (m *process) Run() tea.Cmd {
+	if m.status == statusWaiting {
+		return nil
+	}
+
+	if len(m.dependsOn) > 0 && !m.GetStatus().isActive() {
+		return m.runDependencies()
+	}
+
+	return m.launch()
+}
+
+// runDependencies starts every dependency that isn't already up and parks
+// the process in statusWaiting until they are all ready.
+func (m *process) runDependencies() tea.Cmd {
+	cmds := make([]tea.Cmd, 0, len(m.dependsOn)+1)
+	for _, dep := range m.dependsOn {
+		if dep.needsStart() {
+			cmds = append(cmds, dep.Run())
 		}
-		return lipgloss.NewStyle().Width(m.viewport.Width).Height(m.viewport.Height).Render(sb.String())
-	} else {
-		return m.viewport.View()
 	}
+
+	m.status = statusWaiting
+	cmd := m.checkDependencies()
+	if m.status == statusWaiting {
+		cmd = processTick(m.id)
+	}
+	return tea.Batch(append(cmds, cmd)...)
 }
 
-func (m *process) Run() tea.Cmd {
+// needsStart reports whether a dependency has to be started for a process
+// that depends on it: it never ran, it errored, or it was stopped rather than
+// running to completion.
+func (m *process) needsStart() bool {
+	switch m.GetStatus() {
+	case statusIdle, statusErrored:
+		return true
+	case statusExited:
+		if m.isGroup && !m.stopRequested {
+			for _, cp := range m.children {
+				if cp.needsStart() {
+					return true
+				}
+			}
+			return false
+		}
+		return m.stopRequested
+	}
+	return false
+}
+
+// checkDependencies launches a waiting process once all of its dependencies
+// are ready, or ran to completion, and fails it if one of them errored. A
+// dependency that was stopped in the meantime is waited for until it runs
+// again.
+func (m *process) checkDependencies() tea.Cmd {
+	for _, dep := range m.dependsOn {
+		switch dep.GetStatus() {
+		case statusReady:
+		case statusExited:
+			if dep.needsStart() {
+				return nil
+			}
+		case statusErrored:
+			m.logEvent(logEntry{
+				msg:   fmt.Sprintf("dependency %q errored before becoming ready", dep.name),
+				level: logError,
+			})
+			m.status = statusErrored
+			return nil
+		default:
+			return nil
+		}
+	}
+
+	m.status = statusIdle
+	return m.launch()
+}
+
+// launch starts the process, or the children of a group, without looking at
+// its dependencies.
+func (m *process) launch() tea.Cmd {
 	if m.isGroup {
-		if s := m.GetStatus(); s == statusRunning || s == statusReady {
+		if m.GetStatus().isActive() {
 			return nil
 		}
 		if m.groupType == "parallel" {
@@ -338,128 +982,350 @@ func (m *process) Run() tea.Cmd {
 			for _, cp := range m.children {
 				cmds = append(cmds, cp.Run())
 			}
-			cmds = append(cmds, processTick(m.id))
 			return tea.Batch(cmds...)
 		} else {
 			m.startupChildIndex = 0
-			return tea.Batch(m.children[0].Run(), processTick(m.id))
+			return m.children[0].Run()
 		}
 	}
 
-	if m.status == statusRunning || m.status == statusReady {
-		m.inboxCh <- logEntry{
+	if m.status.isActive() {
+		m.logEvent(logEntry{
 			msg:   fmt.Sprintf("Process %q is already running.", m.name),
 			level: logError,
-		}
+		})
 		return nil
 	}
 
-	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.restartCount = 0
+	m.restartAt = time.Time{}
+	return m.start()
+}
+
+// start launches the process's command. Unlike Run it keeps the restart
+// count so automatic restarts keep backing off.
+func (m *process) start() tea.Cmd {
+	m.stopRequested = false
+	m.ctx, m.cancel = context.WithCancelCause(context.Background())
+
+	if len(m.preStart) > 0 {
+		return m.runPreStart()
+	}
+	return m.exec()
+}
 
-	var err error
+// exec starts the command itself, once its preStart commands, if any, are
+// done.
+func (m *process) exec() tea.Cmd {
+	dir, err := m.resolvePath(m.cwd)
+	if err != nil {
+		m.logEvent(logEntry{
+			msg:   err.Error(),
+			level: logError,
+		})
+		m.statusCh <- statusErrored
+		m.loadViewportFromInbox()
+		return nil
+	}
 
-	// resolve cmd name
-	cmdPath, err := exec.LookPath(m.command[0])
+	// resolve cmd name, relative paths like ./bin/api from the working
+	// directory the command will run in
+	name := m.command[0]
+	if strings.ContainsRune(name, '/') && !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	cmdPath, err := exec.LookPath(name)
 	if err != nil {
-		m.inboxCh <- logEntry{
+		m.logEvent(logEntry{
 			msg:   err.Error(),
 			level: logError,
-		}
+		})
 		m.statusCh <- statusErrored
 		m.loadViewportFromInbox()
 		return nil
 	}
 
 	var cmd *Cmd
-	if len(m.command) > 1 {
-		cmd = NewCommand(m.ctx, cmdPath, m.command[1:]...)
+	if args := m.commandLine()[1:]; len(args) > 0 {
+		cmd = NewCommand(m.ctx, cmdPath, args...)
 	} else {
 		cmd = NewCommand(m.ctx, cmdPath)
 	}
+	cmd.stopSignal = m.stopSignal
+	cmd.stopTimeout = m.stopTimeout
+	cmd.Dir = dir
+	m.cmd = cmd
 
-	cmd.Env = os.Environ()
+	cmd.Env, err = m.environ()
+	if err != nil {
+		m.logEvent(logEntry{
+			msg:   err.Error(),
+			level: logError,
+		})
+		m.statusCh <- statusErrored
+		m.loadViewportFromInbox()
+		return nil
+	}
+	m.env = cmd.Env
 
-	if m.cwd != "" {
-		if filepath.IsAbs(m.cwd) {
-			cmd.Dir = m.cwd
-		} else {
-			cwd, err := os.Getwd()
-			if err == nil {
-				cmd.Dir = filepath.Join(cwd, m.cwd)
-			}
+	// outputs are read into the log, and childEnds closed, once the command
+	// has started
+	var (
+		outputs   []output
+		childEnds []*os.File
+	)
+	m.terminal = nil
+	m.stdin = nil
+	if m.pty {
+		cols, rows := defaultPtyCols, defaultPtyRows
+		if m.isReady {
+			cols, rows = m.viewport.Width, m.viewport.Height
+		}
+		ptmx, tty, err := openPty(cols, rows)
+		if err != nil {
+			m.logEvent(logEntry{
+				msg:   fmt.Sprintf("failed to open a pseudo-terminal: %v", err),
+				level: logError,
+			})
+			m.statusCh <- statusErrored
+			m.loadViewportFromInbox()
+			return nil
 		}
+		// stdout and stderr both end up on the terminal, so everything
+		// is logged as output
+		cmd.Stdin = tty
+		cmd.Stdout = tty
+		cmd.Stderr = tty
+		cmd.terminal = true
+		outputs = []output{{ptyOutput(ptmx), logInfo}}
+		childEnds = []*os.File{tty}
+		m.terminal = ptmx
+		m.stdin = ptmx
 	} else {
-		cwd, err := os.Getwd()
+		// Use our own pipes rather than cmd.StdoutPipe so that Wait doesn't
+		// close them while the readers are still draining the last lines.
+		stdout, stdoutW, err := os.Pipe()
 		if err != nil {
-			m.inboxCh <- logEntry{
+			m.logEvent(logEntry{
 				msg:   err.Error(),
 				level: logError,
-			}
+			})
+			m.statusCh <- statusErrored
+			m.loadViewportFromInbox()
+			return nil
+		}
+		stderr, stderrW, err := os.Pipe()
+		if err != nil {
+			stdout.Close()
+			stdoutW.Close()
+			m.logEvent(logEntry{
+				msg:   err.Error(),
+				level: logError,
+			})
+			m.statusCh <- statusErrored
+			m.loadViewportFromInbox()
+			return nil
+		}
+		stdin, stdinW, err := os.Pipe()
+		if err != nil {
+			stdout.Close()
+			stdoutW.Close()
+			stderr.Close()
+			stderrW.Close()
+			m.logEvent(logEntry{
+				msg:   err.Error(),
+				level: logError,
+			})
 			m.statusCh <- statusErrored
 			m.loadViewportFromInbox()
+			return nil
 		}
-		cmd.Dir = cwd
+		cmd.Stdin = stdin
+		cmd.Stdout = stdoutW
+		cmd.Stderr = stderrW
+		outputs = []output{{stdout, logInfo}, {stderr, logError}}
+		childEnds = []*os.File{stdin, stdoutW, stderrW}
+		m.stdin = stdinW
 	}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		m.inboxCh <- logEntry{
-			msg:   err.Error(),
-			level: logError,
-		}
-		m.statusCh <- statusErrored
-		m.loadViewportFromInbox()
-		return nil
+	err = cmd.Start()
+	// the child holds its own copies of these now
+	for _, f := range childEnds {
+		f.Close()
 	}
-	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		m.inboxCh <- logEntry{
+		for _, o := range outputs {
+			o.r.Close()
+		}
+		if m.stdin != nil {
+			m.stdin.Close()
+		}
+		m.terminal = nil
+		m.stdin = nil
+		m.logEvent(logEntry{
 			msg:   err.Error(),
 			level: logError,
-		}
+		})
 		m.statusCh <- statusErrored
 		m.loadViewportFromInbox()
 		return nil
 	}
 
-	err = cmd.Start()
-	if err != nil {
-		m.inboxCh <- logEntry{
-			msg:   err.Error(),
-			level: logError,
+	var lf *logFile
+	if m.logFile != "" {
+		path, err := m.resolvePath(m.logFile)
+		if err == nil {
+			lf, err = openLogFile(path, m.logMaxSize, m.logMaxFiles)
 		}
-		m.statusCh <- statusErrored
-		m.loadViewportFromInbox()
-		return nil
+		if err != nil {
+			m.logEvent(logEntry{
+				msg:   fmt.Sprintf("failed to open log file: %v", err),
+				level: logError,
+			})
+		}
+	}
+
+	m.run = runInfo{
+		pid:     cmd.Process.Pid,
+		started: time.Now(),
+		count:   m.run.count + 1,
 	}
 
-	if m.readyRegexp != nil {
+	m.dir = cmd.Dir
+	runCtx, runCancel := context.WithCancel(m.ctx)
+	m.runCtx = runCtx
+	m.livenessStarted = false
+
+	if m.readyRegexp != nil || m.readyCheck != nil || m.readyOnPort {
 		m.status = statusRunning
 	} else {
 		m.status = statusReady
+		m.startLivenessCheck()
+	}
+
+	if m.readyCheck != nil {
+		go m.readyCheck.waitReady(runCtx, m.cancel, cmd.Dir, cmd.Env, m.statusCh, m.notify)
 	}
 
-	go streamPipeToChan(stdout, m.inboxCh, m.readyRegexp, m.statusCh, logInfo)
-	go streamPipeToChan(stderr, m.inboxCh, m.readyRegexp, m.statusCh, logError)
+	in := m.inbox()
+	var streams sync.WaitGroup
+	for _, o := range outputs {
+		streams.Add(1)
+		go func() {
+			defer streams.Done()
+			defer o.r.Close()
+			streamPipeToChan(o.r, in, m.readyRegexp, m.statusCh, o.level, lf)
+		}()
+	}
+	streamsDone := make(chan struct{})
+	go func() {
+		streams.Wait()
+		close(streamsDone)
+	}()
 
+	ctx, postStop, stdin := m.ctx, m.postStop, m.stdin
 	go func() {
 		err := cmd.Wait()
-		if err != nil {
-			m.inboxCh <- logEntry{
+		if !m.pty {
+			// writing to a command that is gone fails rather than filling
+			// the pipe; a terminal is closed once its output is read
+			stdin.Close()
+		}
+		exit := exitInfo{ended: time.Now(), exitCode: cmd.ProcessState.ExitCode()}
+		if exit.exitCode < 0 && cmd.ProcessState != nil {
+			exit.signal = exitSignal(cmd.ProcessState)
+		}
+		runCancel()
+
+		select {
+		case <-streamsDone:
+		case <-time.After(streamDrainTimeout):
+		}
+
+		var (
+			entry  logEntry
+			status processStatus
+		)
+		if cause := context.Cause(ctx); err != nil && cause != nil && cause != context.Canceled {
+			entry = logEntry{
+				msg:   fmt.Sprintf("%v (%v)", cause, err),
+				level: logError,
+			}
+			status = statusErrored
+		} else if err != nil && ctx.Err() != nil {
+			entry = logEntry{
+				msg:   fmt.Sprintf("stopped (%v)", err),
+				level: logInfo,
+			}
+			status = statusExited
+		} else if err != nil {
+			entry = logEntry{
 				msg:   fmt.Sprintf("%v", err),
 				level: logError,
 			}
-			m.statusCh <- statusErrored
+			status = statusErrored
 		} else {
-			m.inboxCh <- logEntry{
+			entry = logEntry{
 				msg:   "exited with code 0",
 				level: logInfo,
 			}
-			m.statusCh <- statusExited
+			status = statusExited
+		}
+
+		if lf != nil {
+			lf.writeLine(time.Now(), "sheepdog", entry.msg)
+			lf.Close()
+		}
+		m.inboxCh <- entry
+
+		// the cleanup is done by the time the process counts as stopped,
+		// so that a restart doesn't race with it
+		if len(postStop) > 0 {
+			hookCtx, cancel := context.WithTimeout(context.Background(), postStopTimeout)
+			if err := runHooks(hookCtx, "postStop", postStop, cmd.Dir, cmd.Env, in); err != nil {
+				m.inboxCh <- logEntry{
+					msg:   err.Error(),
+					level: logError,
+				}
+			}
+			cancel()
 		}
+
+		// the exit has to be known by the time the status changes
+		m.exitCh <- exit
+		m.statusCh <- status
+		m.notify()
 	}()
 
-	return processTick(m.id)
+	return nil
+}
+
+// commandLine is the command the process runs, with its arguments replaced
+// when the active profile overrides them.
+func (m *process) commandLine() []string {
+	if m.override.Args == nil {
+		return m.command
+	}
+	return append([]string{m.command[0]}, m.override.Args...)
+}
+
+// resolvePath resolves a relative path from the config against the directory
+// of the config file, or the directory sheepdog was started in when there is
+// none.
+func (m *process) resolvePath(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	base := m.baseDir
+	if base == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		base = cwd
+	}
+	return filepath.Join(base, path), nil
 }
 
 func (m *process) Kill() tea.Cmd {
@@ -468,10 +1334,28 @@ func (m *process) Kill() tea.Cmd {
 			cp.Kill()
 		}
 
+		if m.status == statusWaiting {
+			m.Cancel()
+		}
+		return nil
+	}
+
+	// a restart waiting out its backoff is called off
+	if m.status == statusWaiting || !m.restartAt.IsZero() {
+		m.Cancel()
 		return nil
 	}
 
-	if m.status != statusRunning && m.status != statusReady {
+	if m.status == statusStopping {
+		// a second kill while waiting on the stop timeout skips the rest
+		// of the grace period
+		if m.cmd != nil {
+			m.cmd.killProcessTree()
+		}
+		return nil
+	}
+
+	if !m.status.isActive() {
 		return nil
 	}
 
@@ -479,24 +1363,91 @@ func (m *process) Kill() tea.Cmd {
 	return nil
 }
 
-var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[ -~]|\x1b\][^\a]*\a|\x1b\][^\x1b]*\x1b\\`)
+// Restart stops the process, or every process in a group, and runs it again
+// once everything has exited. A process that isn't running is simply run.
+func (m *process) Restart() tea.Cmd {
+	s := m.GetStatus()
+	if !s.isActive() && s != statusWaiting {
+		return m.Run()
+	}
+
+	var cmd tea.Cmd
+	if s != statusStopping {
+		cmd = m.Kill()
+	}
+	m.restartAfterStop = true
+	return cmd
+}
+
+// scheduleRestart applies the restart policy to a process that has just
+// stopped, returning the command that will start it again after the backoff.
+func (m *process) scheduleRestart() tea.Cmd {
+	if m.stopRequested {
+		return nil
+	}
+
+	switch m.restartPolicy {
+	case restartAlways:
+	case restartOnFailure:
+		if m.status != statusErrored {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if m.maxRestarts > 0 && m.restartCount >= m.maxRestarts {
+		m.logEvent(logEntry{
+			msg:   fmt.Sprintf("giving up after %d restarts", m.restartCount),
+			level: logError,
+		})
+		return nil
+	}
+
+	backoff := m.restartDelay()
+	m.restartCount++
+	m.restartAt = time.Now().Add(backoff)
+	m.restartGen++
+
+	m.logEvent(logEntry{
+		msg:   fmt.Sprintf("restarting in %s (restart %d)", backoff, m.restartCount),
+		level: logInfo,
+	})
+
+	return restartTick(m.id, m.restartGen, backoff)
+}
+
+// restartDelay is the backoff before the next automatic restart, which doubles
+// with every restart up to restartMaxBackoff.
+func (m *process) restartDelay() time.Duration {
+	backoff := m.restartBackoff
+	for i := 0; i < m.restartCount && backoff < m.restartMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, m.restartMaxBackoff)
+}
 
-// oscSequence matches OSC escape sequences (terminal title changes, etc.),
-// including ones left unterminated when a line is split by the scanner. These
-// must not reach the viewport or they leak through to the host terminal and
-// override sheepdog's own window title.
-var oscSequence = regexp.MustCompile(`\x1b\](?:[^\a\x1b]*\a|[^\x1b]*\x1b\\|[^\a\x1b]*$)`)
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[ -~]|\x1b\][^\a]*\a|\x1b\][^\x1b]*\x1b\\`)
 
 func stripControlSequences(input string) string {
 	return ansiSequence.ReplaceAllString(input, "")
 }
 
-func streamPipeToChan(r io.ReadCloser, ch chan logEntry, readyRegex *regexp.Regexp, statusCh chan processStatus, level logLevel) {
+// streamPipeToChan reads r line by line into in. Lines are run through a term
+// first, so they arrive the way a terminal would show them. When file is set,
+// every line is written to it before any dropping can happen.
+func streamPipeToChan(r io.ReadCloser, in inbox, readyRegex *regexp.Regexp, statusCh chan processStatus, level logLevel, file *logFile) {
+	stream := "stdout"
+	if level == logError {
+		stream = "stderr"
+	}
+
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineBytes)
 	isReady := false
+	vt := &term{}
 	for scanner.Scan() {
-		line := oscSequence.ReplaceAllString(scanner.Text(), "")
+		line := vt.line(scanner.Text())
 
 		if readyRegex != nil && !isReady {
 			superClean := stripControlSequences(line)
@@ -504,26 +1455,23 @@ func streamPipeToChan(r io.ReadCloser, ch chan logEntry, readyRegex *regexp.Rege
 			if match {
 				statusCh <- statusReady
 				isReady = true
+				if in.wake != nil {
+					in.wake()
+				}
 			}
 		}
 
-		entry := logEntry{msg: line, level: level}
-		select {
-		case ch <- entry:
-		default:
-			// Drop the log line if the buffer is full to avoid
-			// blocking the reader. This ensures the process stdout
-			// is continually drained even when the UI is busy.
+		now := time.Now()
+		if file != nil {
+			file.writeLine(now, stream, stripControlSequences(line))
 		}
+
+		in.send(logEntry{msg: line, level: level, timestamp: now})
 	}
 	if err := scanner.Err(); err != nil {
-		entry := logEntry{
+		in.send(logEntry{
 			msg:   fmt.Sprintf("log streaming stopped: %v", err),
 			level: logError,
-		}
-		select {
-		case ch <- entry:
-		default:
-		}
+		})
 	}
 }
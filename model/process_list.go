@@ -2,73 +2,161 @@ package model
 
 import (
 	"fmt"
-	"log"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/control"
 	"github.com/steventhorne/sheepdog/input"
 	"github.com/steventhorne/sheepdog/style"
 )
 
+// redrawInterval is the time between two redraws while a process is active,
+// which keeps the uptimes shown current when nothing else happens.
+const redrawInterval = time.Second
+
+type redrawMsg struct{}
+
 type processList struct {
-	processes            []*process
+	processes []*process
+	// all is the virtual entry at the top of the list that shows the
+	// output of every process together.
+	all                  *process
 	selectedProcessIndex int
 	selectedProcess      *process
+	byName               map[string]*process
 	version              string
+	// selection picks the processes started on launch, or by the last
+	// profile switch.
+	selection Selection
+	profiles  map[string]config.Profile
+
+	// picking is set while the profile picker is open, with pickerIndex
+	// the highlighted entry of profileNames.
+	picking     bool
+	pickerIndex int
+
+	// prompt is the kind of text being typed for the selected process, if
+	// any, and promptText what has been typed so far.
+	prompt     promptMode
+	promptText string
+
+	// attached is the process keys are forwarded to, until detachKey is
+	// pressed. While attached promptText is the line being typed.
+	attached  *process
+	detachKey string
+
+	// program is the program the list runs in, which its processes are
+	// woken up through.
+	program *programRef
+	// redrawing is set while a redrawMsg is on its way.
+	redrawing bool
 }
 
+// newProcessList builds the process tree for a config that passed
+// config.Validate.
 func newProcessList(config config.Config) processList {
 	pl := processList{
 		processes: make([]*process, 0),
+		byName:    make(map[string]*process),
+		profiles:  config.Profiles,
+		detachKey: config.DetachKey,
+		program:   &programRef{},
+	}
+	if pl.detachKey == "" {
+		pl.detachKey = defaultDetachKey
 	}
 
-	seen := make(map[string]struct{})
 	for _, pConfig := range config.Processes {
-		p := pl.getProcessFromConfig(pConfig, nil, seen)
+		p := pl.getProcessFromConfig(pConfig, nil, pl.byName)
 
 		pl.processes = append(pl.processes, p)
 	}
 
-	if len(pl.processes) > 0 {
-		pl.selectedProcessIndex = 0
-		pl.selectedProcess = pl.processes[0]
+	pl.resolveDependencies()
+
+	pl.all = newAllProcess(pl.processes)
+	pl.all.program = pl.program
+	for _, p := range pl.byName {
+		p.program = pl.program
 	}
 
-	return pl
-}
+	for i, p := range pl.leaves() {
+		p.color = style.ProcessColors[i%len(style.ProcessColors)]
+		p.baseDir = config.Dir
 
-func (m *processList) getProcessFromConfig(pConfig config.ProcessConfig, parent *process, seen map[string]struct{}) *process {
-	isCommand := len(pConfig.Command) > 0
-	isGroup := pConfig.GroupType != "" && len(pConfig.Children) > 0
-	if isCommand && isGroup {
-		log.Fatalf("Command %s is configured as both a command and a group. This is not allowed", pConfig.Name)
+		if p.logFile == "" && config.LogDir != "" {
+			p.logFile = filepath.Join(config.LogDir, p.name+".log")
+		}
+		if config.LogMaxSize > 0 {
+			p.logMaxSize = int64(config.LogMaxSize)
+		}
+		if config.LogMaxFiles > 0 {
+			p.logMaxFiles = config.LogMaxFiles
+		}
 	}
 
-	if !isCommand && !isGroup {
-		log.Fatalf("Command %s is configured as neither a command nor a group. This is not allowed", pConfig.Name)
+	// log files can be anywhere, including under the cwd another process
+	// watches
+	var logFiles []string
+	for _, p := range pl.leaves() {
+		if p.logFile == "" {
+			continue
+		}
+		if path, err := p.resolvePath(p.logFile); err == nil {
+			logFiles = append(logFiles, filepath.Clean(path))
+		}
 	}
-
-	if isGroup && pConfig.GroupType != "sequential" && pConfig.GroupType != "parallel" {
-		log.Fatalf("Invalid group type for group %s", pConfig.Name)
+	for _, p := range pl.leaves() {
+		if p.watch != nil {
+			p.watch.logFiles = logFiles
+		}
 	}
 
-	if _, ok := seen[pConfig.Name]; ok {
-		log.Fatalf("Duplicate process name %q. Names must be unique", pConfig.Name)
+	pl.selectedProcess = pl.all
+	if len(pl.processes) > 0 {
+		// the first real process, below the all entry
+		pl.selectedProcessIndex = 1
+		pl.selectedProcess = pl.processes[0]
 	}
-	seen[pConfig.Name] = struct{}{}
+
+	return pl
+}
+
+// newAllProcess creates the all entry: a parallel group of the top-level
+// processes that isn't their parent, so they don't know it's there.
+func newAllProcess(processes []*process) *process {
+	p := newProcess(config.ProcessConfig{Name: "all", GroupType: "parallel"})
+	p.isAll = true
+	p.isGroup = true
+	p.children = processes
+	return p
+}
+
+func (m *processList) getProcessFromConfig(pConfig config.ProcessConfig, parent *process, seen map[string]*process) *process {
+	isGroup := pConfig.GroupType != "" && len(pConfig.Children) > 0
 
 	p := newProcess(pConfig)
 	p.isGroup = isGroup
+	seen[pConfig.Name] = p
 
 	if parent != nil {
+		p.parent = parent
 		parent.children = append(parent.children, p)
 
 		// override cwd if we don't explicitly have one and the parent does
 		if parent.cwd != "" && p.cwd == "" {
 			p.cwd = parent.cwd
 		}
+
+		// inherit the parent's env, letting our own values win
+		if len(parent.envSources) > 0 {
+			p.envSources = append(append([]envSource{}, parent.envSources...), p.envSources...)
+		}
 	}
 
 	if isGroup {
@@ -80,14 +168,66 @@ func (m *processList) getProcessFromConfig(pConfig config.ProcessConfig, parent
 	return p
 }
 
+// resolveDependencies links every process to the processes named in its
+// dependsOn. Unknown names and cycles were already rejected by
+// config.Validate.
+func (m *processList) resolveDependencies() {
+	for _, p := range m.byName {
+		for _, name := range p.dependsOnNames {
+			if dep, ok := m.byName[name]; ok {
+				p.dependsOn = append(p.dependsOn, dep)
+			}
+		}
+	}
+}
+
+// leaves returns every process that runs a command, in list order.
+func (m *processList) leaves() []*process {
+	var leaves []*process
+	var walk func(ps []*process)
+	walk = func(ps []*process) {
+		for _, p := range ps {
+			if p.isGroup {
+				walk(p.children)
+			} else {
+				leaves = append(leaves, p)
+			}
+		}
+	}
+	walk(m.processes)
+	return leaves
+}
+
+// settled reports whether nothing is running or about to run, and all output
+// has been collected. A process that watches its files may run again at any
+// time until it is stopped.
+func (m *processList) settled() bool {
+	for _, p := range m.byName {
+		if p.GetStatus().isActive() || p.status == statusWaiting || !p.restartAt.IsZero() || p.restartAfterStop {
+			return false
+		}
+		if p.watchCh != nil && p.status != statusIdle && !p.stopRequested {
+			return false
+		}
+		if len(p.inboxCh) > 0 || len(p.statusCh) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// setProgram hands the processes the program they are woken up through.
+func (m *processList) setProgram(p *tea.Program) {
+	m.program.p.Store(p)
+}
+
 func (m *processList) GetSelectedProcess() *process {
 	return m.selectedProcess
 }
 
 func (m *processList) AllStopped() bool {
 	for _, p := range m.processes {
-		s := p.GetStatus()
-		if s == statusRunning || s == statusReady {
+		if p.GetStatus().isActive() {
 			return false
 		}
 	}
@@ -95,21 +235,32 @@ func (m *processList) AllStopped() bool {
 }
 
 func (m *processList) Init() tea.Cmd {
-	if len(m.processes) > 0 {
-		m.selectedProcess = m.processes[0]
-		m.selectedProcess.isSelected = true
-	}
+	m.selectedProcess = m.GetNthProcess(m.selectedProcessIndex, false)
+	m.selectedProcess.isSelected = true
 
 	cmds := make([]tea.Cmd, 0, len(m.processes)+1)
 	for _, p := range m.processes {
-		cmd := p.Init()
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, m.selection.run(p))
+	}
+	cmds = append(cmds, m.startWatching(), m.sampleUsage())
+	return tea.Batch(cmds...)
+}
+
+// startWatching starts the file watchers of every process that has one.
+func (m *processList) startWatching() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, p := range m.leaves() {
+		cmds = append(cmds, p.startWatching())
 	}
 	return tea.Batch(cmds...)
 }
 
 func (m *processList) GetNthProcess(n int, includeHidden bool) *process {
-	cur := -1
+	if n == 0 {
+		return m.all
+	}
+
+	cur := 0
 	for _, p := range m.processes {
 		if p == nil {
 			continue
@@ -161,18 +312,126 @@ func (m *processList) getNthProcessFromProcess(p *process, cur int, n int, inclu
 	return nil, cur
 }
 
+// updatePrompt feeds a key to the search, filter or input prompt.
+func (m *processList) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	p := m.selectedProcess
+
+	switch msg.Type {
+	case tea.KeyEnter:
+		if m.prompt == promptInput {
+			err := p.sendLine(m.promptText)
+			m.closePrompt()
+			if err != nil {
+				p.logInputError(err)
+			}
+			return nil
+		}
+
+		var err error
+		if m.prompt == promptSearch {
+			err = p.search.setPattern(m.promptText)
+		} else {
+			err = p.search.setFilter(m.promptText)
+		}
+		if err != nil {
+			p.prompt = fmt.Sprintf("%s%s  (invalid regexp)", m.prompt.prefix(), m.promptText)
+			return nil
+		}
+
+		mode := m.prompt
+		m.closePrompt()
+		p.loadViewportFromInbox()
+		if mode == promptSearch {
+			p.jumpToMatch(true)
+		}
+		return nil
+	case tea.KeyEsc:
+		m.closePrompt()
+		return nil
+	case tea.KeyBackspace:
+		if m.promptText == "" {
+			m.closePrompt()
+			return nil
+		}
+		runes := []rune(m.promptText)
+		m.promptText = string(runes[:len(runes)-1])
+	case tea.KeySpace:
+		m.promptText += " "
+	case tea.KeyRunes:
+		m.promptText += string(msg.Runes)
+	}
+
+	p.prompt = m.prompt.prefix() + m.promptText + "█"
+	return nil
+}
+
+func (m *processList) openPrompt(mode promptMode) {
+	m.prompt = mode
+	m.promptText = ""
+	m.selectedProcess.prompt = mode.prefix() + "█"
+}
+
+func (m *processList) closePrompt() {
+	m.prompt = promptNone
+	m.promptText = ""
+	m.selectedProcess.prompt = ""
+}
+
 func (m *processList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	cmds := make([]tea.Cmd, 0, len(m.processes)+1)
+	// an attached process gets every key, quit included, until detached
+	if msg, ok := msg.(tea.KeyMsg); ok && m.attached != nil {
+		return m, m.updateAttached(msg)
+	}
+	// while a prompt is open it takes every key except quit
+	if msg, ok := msg.(tea.KeyMsg); ok && m.prompt != promptNone {
+		if !key.Matches(msg, input.DefaultKeyMap.Quit) {
+			return m, m.updatePrompt(msg)
+		}
+		m.closePrompt()
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok && m.picking && !key.Matches(msg, input.DefaultKeyMap.Quit) {
+		return m, m.updatePicker(msg)
+	}
+
+	cmds := make([]tea.Cmd, 0, len(m.processes)+2)
 	for _, p := range m.processes {
 		_, cmd := p.Update(msg)
 		cmds = append(cmds, cmd)
 	}
 
+	// the all entry shares its children with the list, which have just been
+	// updated, so it only looks after its own view
+	if _, ok := msg.(processMsg); ok && m.all.isSelected {
+		m.all.loadViewportFromInbox()
+	}
+	cmds = append(cmds, m.all.updateViewport(msg))
+
+	if m.attached != nil && !m.attached.status.isActive() {
+		m.detach()
+	}
+
+	// whatever is still waiting on the channels, such as more lines than
+	// are pulled at once, is pulled in with another wakeup
+	for _, p := range m.byName {
+		if p.hasPending() {
+			p.notify()
+		}
+	}
+
 	switch msg := msg.(type) {
+	case usageMsg:
+		if !msg.unsupported {
+			m.applyUsage(msg)
+			cmds = append(cmds, m.sampleUsage())
+		}
+	case control.Message:
+		res, cmd := m.handleControl(msg.Request)
+		msg.Reply <- res
+		cmds = append(cmds, cmd)
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, input.DefaultKeyMap.Enter):
-			if m.selectedProcess != nil {
+			if m.selectedProcess != nil && !m.selectedProcess.isAll {
 				m.selectedProcess.isFocused = !m.selectedProcess.isFocused
 			}
 		case key.Matches(msg, input.DefaultKeyMap.Quit):
@@ -191,6 +450,8 @@ func (m *processList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedProcess = np
 				m.selectedProcess.isSelected = true
 				m.selectedProcessIndex = tmp
+				// only the selected log is kept laid out
+				np.loadViewportFromInbox()
 			}
 		case key.Matches(msg, input.DefaultKeyMap.Up):
 			if m.selectedProcess != nil && !m.selectedProcess.isGroup && m.selectedProcess.isFocused {
@@ -204,11 +465,77 @@ func (m *processList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedProcess = np
 				m.selectedProcess.isSelected = true
 				m.selectedProcessIndex = tmp
+				// only the selected log is kept laid out
+				np.loadViewportFromInbox()
 			}
 		case key.Matches(msg, input.DefaultKeyMap.Run):
 			if m.selectedProcess != nil {
 				cmds = append(cmds, m.selectedProcess.Run())
 			}
+		case key.Matches(msg, input.DefaultKeyMap.Env):
+			if m.selectedProcess != nil && !m.selectedProcess.isGroup {
+				m.selectedProcess.showEnv = !m.selectedProcess.showEnv
+				m.selectedProcess.loadViewportFromInbox()
+			}
+		case key.Matches(msg, input.DefaultKeyMap.History):
+			if p := m.selectedProcess; p != nil && !p.isGroup {
+				p.showHistory = !p.showHistory
+				if !p.showHistory {
+					p.log.dropHistory()
+				}
+				p.loadViewportFromInbox()
+			}
+		case key.Matches(msg, input.DefaultKeyMap.Search):
+			if m.selectedProcess != nil {
+				m.openPrompt(promptSearch)
+			}
+		case key.Matches(msg, input.DefaultKeyMap.Filter):
+			if m.selectedProcess != nil {
+				m.openPrompt(promptFilter)
+			}
+		case key.Matches(msg, input.DefaultKeyMap.Attach):
+			cmds = append(cmds, m.attach())
+		case key.Matches(msg, input.DefaultKeyMap.SendInput):
+			if p := m.selectedProcess; p != nil && !p.isGroup {
+				if p.stdin == nil || !p.status.isActive() {
+					p.logInputError(fmt.Errorf("%s isn't running", p.name))
+				} else {
+					m.openPrompt(promptInput)
+				}
+			}
+		case key.Matches(msg, input.DefaultKeyMap.NextMatch):
+			if m.selectedProcess != nil {
+				m.selectedProcess.jumpToMatch(true)
+			}
+		case key.Matches(msg, input.DefaultKeyMap.PrevMatch):
+			if m.selectedProcess != nil {
+				m.selectedProcess.jumpToMatch(false)
+			}
+		case key.Matches(msg, input.DefaultKeyMap.StderrOnly):
+			if m.selectedProcess != nil {
+				m.selectedProcess.search.stderrOnly = !m.selectedProcess.search.stderrOnly
+				m.selectedProcess.loadViewportFromInbox()
+			}
+		case key.Matches(msg, input.DefaultKeyMap.ClearSearch):
+			if m.selectedProcess != nil {
+				m.selectedProcess.search = logSearch{current: -1}
+				m.selectedProcess.loadViewportFromInbox()
+				m.selectedProcess.viewport.GotoBottom()
+			}
+		case key.Matches(msg, input.DefaultKeyMap.Profiles):
+			if len(m.profiles) > 0 {
+				m.openPicker()
+			}
+		case key.Matches(msg, input.DefaultKeyMap.CopyURL):
+			if p := m.selectedProcess; p != nil && len(p.usage.ports) > 0 {
+				url := fmt.Sprintf("http://localhost:%d", p.usage.ports[0])
+				p.logEvent(logEntry{
+					msg:   fmt.Sprintf("copied %s to the clipboard", url),
+					level: logInfo,
+				})
+				p.loadViewportFromInbox()
+				cmds = append(cmds, copyToClipboard(url))
+			}
 		case key.Matches(msg, input.DefaultKeyMap.Kill):
 			if m.selectedProcess != nil {
 				cmd := m.selectedProcess.Kill()
@@ -218,6 +545,16 @@ func (m *processList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 	}
+
+	if _, ok := msg.(redrawMsg); ok {
+		m.redrawing = false
+	}
+	if !m.redrawing && !m.AllStopped() {
+		m.redrawing = true
+		cmds = append(cmds, tea.Tick(redrawInterval, func(t time.Time) tea.Msg {
+			return redrawMsg{}
+		}))
+	}
 	return m, tea.Batch(cmds...)
 }
 
@@ -225,7 +562,7 @@ func writeListViewForProcess(psb *strings.Builder, p *process, prefix string) {
 	var sb strings.Builder
 
 	sb.WriteString(prefix)
-	if p.isGroup {
+	if p.isGroup && !p.isAll {
 		if p.isFocused {
 			sb.WriteString("⯆ ")
 		} else {
@@ -246,6 +583,15 @@ func writeListViewForProcess(psb *strings.Builder, p *process, prefix string) {
 	case statusReady:
 		itemStyle = style.StyleItemReady
 		sb.WriteString(" R ")
+	case statusWaiting:
+		itemStyle = style.StyleItemWaiting
+		sb.WriteString(" W ")
+	case statusUnhealthy:
+		itemStyle = style.StyleItemUnhealthy
+		sb.WriteString(" U ")
+	case statusStopping:
+		itemStyle = style.StyleItemStopping
+		sb.WriteString(" T ")
 	case statusErrored:
 		itemStyle = style.StyleItemErrored
 		sb.WriteString(" E ")
@@ -257,10 +603,24 @@ func writeListViewForProcess(psb *strings.Builder, p *process, prefix string) {
 
 	sb.WriteString(p.name)
 
+	if short := p.run.short(time.Now()); short != "" && !p.isGroup {
+		fmt.Fprintf(&sb, " %s", short)
+	}
+	if usage := p.usage.short(); usage != "" {
+		fmt.Fprintf(&sb, " %s", usage)
+	}
+	if p.restartCount > 0 {
+		fmt.Fprintf(&sb, " ↻%d", p.restartCount)
+	}
+	if !p.restartAt.IsZero() {
+		fmt.Fprintf(&sb, " in %s", time.Until(p.restartAt).Round(time.Second))
+	}
+
 	if p.isSelected {
 		itemStyle = itemStyle.Reverse(true)
 	}
-	psb.WriteString(itemStyle.Render(sb.String()))
+	// a row that wrapped would shift the rest of the list down
+	psb.WriteString(itemStyle.Render(ansi.Truncate(sb.String(), style.WidthSidenav, "…")))
 	psb.WriteString("\n")
 
 	if p.isGroup && p.isFocused {
@@ -271,11 +631,21 @@ func writeListViewForProcess(psb *strings.Builder, p *process, prefix string) {
 }
 
 func (m *processList) View() string {
+	version := style.StyleVersion.Render("sheepdog " + m.version)
+	if m.picking {
+		return fmt.Sprintf("%s\n%s\n%s", version, style.StyleListHeader.Render("Profiles"), style.StyleList.Render(m.pickerView()))
+	}
+
 	var sb strings.Builder
 
+	writeListViewForProcess(&sb, m.all, "")
 	for _, p := range m.processes {
 		writeListViewForProcess(&sb, p, "")
 	}
 
-	return fmt.Sprintf("%s\n%s\n%s", style.StyleVersion.Render("sheepdog "+m.version), style.StyleListHeader.Render("Processes"), style.StyleList.Render(sb.String()))
+	header := "Processes"
+	if m.selection.Profile != "" {
+		header += " (" + m.selection.Profile + ")"
+	}
+	return fmt.Sprintf("%s\n%s\n%s", version, style.StyleListHeader.Render(header), style.StyleList.Render(sb.String()))
 }
@@ -2,9 +2,13 @@ package model
 
 import (
 	"io"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestStreamPipeToChan(t *testing.T) {
@@ -12,7 +16,7 @@ func TestStreamPipeToChan(t *testing.T) {
 	statusCh := make(chan processStatus, 1)
 	r := io.NopCloser(strings.NewReader("foo\nbar\n"))
 
-	streamPipeToChan(r, ch, nil, statusCh, logInfo)
+	streamPipeToChan(r, inbox{ch: ch}, nil, statusCh, logInfo, nil)
 	close(ch)
 
 	var entries []logEntry
@@ -36,7 +40,7 @@ func TestStreamPipeToChanSignalsReadyOnce(t *testing.T) {
 	statusCh := make(chan processStatus, 10)
 	r := io.NopCloser(strings.NewReader("loaded\nloaded\nloaded\n"))
 
-	streamPipeToChan(r, ch, regexp.MustCompile("^loaded$"), statusCh, logInfo)
+	streamPipeToChan(r, inbox{ch: ch}, regexp.MustCompile("^loaded$"), statusCh, logInfo, nil)
 	close(statusCh)
 
 	var statuses []processStatus
@@ -60,7 +64,7 @@ func TestStreamPipeToChanStripsOscSequences(t *testing.T) {
 			"bar\x1b]2;title\x1b\\baz\n" +
 			"\x1b[31mred\x1b[0m\x1b]0;unterminated\n"))
 
-	streamPipeToChan(r, ch, nil, statusCh, logInfo)
+	streamPipeToChan(r, inbox{ch: ch}, nil, statusCh, logInfo, nil)
 	close(ch)
 
 	var entries []logEntry
@@ -82,12 +86,41 @@ func TestStreamPipeToChanStripsOscSequences(t *testing.T) {
 	}
 }
 
+func TestStreamPipeToChanWritesLogFileBeforeDropping(t *testing.T) {
+	ch := make(chan logEntry, 1)
+	statusCh := make(chan processStatus, 1)
+	r := io.NopCloser(strings.NewReader("foo\n\x1b[31mbar\x1b[0m\n"))
+
+	path := filepath.Join(t.TempDir(), "proc.log")
+	lf, err := openLogFile(path, defaultLogMaxSize, defaultLogMaxFiles)
+	if err != nil {
+		t.Fatalf("openLogFile returned error: %v", err)
+	}
+
+	var dropped atomic.Int64
+	streamPipeToChan(r, inbox{ch: ch, dropped: &dropped}, nil, statusCh, logError, lf)
+	lf.Close()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines in log file, got %d: %q", len(lines), content)
+	}
+	if !strings.HasSuffix(lines[0], " stderr | foo") || !strings.HasSuffix(lines[1], " stderr | bar") {
+		t.Fatalf("unexpected log file content: %q", content)
+	}
+}
+
 func TestStreamPipeToChanDropsWhenFull(t *testing.T) {
 	ch := make(chan logEntry, 1)
 	statusCh := make(chan processStatus, 1)
-	r := io.NopCloser(strings.NewReader("foo\nbar\n"))
+	r := io.NopCloser(strings.NewReader("foo\nbar\nbaz\n"))
 
-	streamPipeToChan(r, ch, nil, statusCh, logInfo)
+	var dropped atomic.Int64
+	streamPipeToChan(r, inbox{ch: ch, dropped: &dropped}, nil, statusCh, logInfo, nil)
 	close(ch)
 
 	var entries []logEntry
@@ -101,4 +134,124 @@ func TestStreamPipeToChanDropsWhenFull(t *testing.T) {
 	if entries[0].msg != "foo" {
 		t.Fatalf("unexpected log entry: %#v", entries)
 	}
+	if n := dropped.Load(); n != 2 {
+		t.Fatalf("expected 2 dropped lines, got %d", n)
+	}
+}
+
+func TestStreamPipeToChanWaitsWhenLossless(t *testing.T) {
+	ch := make(chan logEntry, 1)
+	statusCh := make(chan processStatus, 1)
+	r := io.NopCloser(strings.NewReader("foo\nbar\nbaz\n"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		streamPipeToChan(r, inbox{ch: ch}, nil, statusCh, logInfo, nil)
+	}()
+
+	var msgs []string
+	for len(msgs) < 3 {
+		select {
+		case e := <-ch:
+			msgs = append(msgs, e.msg)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for lines, got %q", msgs)
+		}
+	}
+	<-done
+
+	if strings.Join(msgs, "|") != "foo|bar|baz" {
+		t.Fatalf("unexpected log lines: %q", msgs)
+	}
+}
+
+func TestPullInboxMarksDroppedLines(t *testing.T) {
+	p := &process{inboxCh: make(chan logEntry, 2)}
+	p.inboxCh <- logEntry{msg: "foo"}
+	p.dropped.Store(3)
+
+	p.pullInbox()
+
+	entries := p.log.entries()
+	if len(entries) != 2 || entries[0].msg != "foo" {
+		t.Fatalf("unexpected log: %#v", entries)
+	}
+	if entries[1].msg != "--- 3 lines dropped ---" || entries[1].level != logError {
+		t.Fatalf("expected a dropped marker, got %#v", entries[1])
+	}
+	if p.dropped.Load() != 0 {
+		t.Fatal("expected the dropped count to be reset")
+	}
+}
+
+func TestLogEventDoesNotBlockOnAFullInbox(t *testing.T) {
+	p := &process{inboxCh: make(chan logEntry, 1), lossless: true}
+	p.inboxCh <- logEntry{msg: "output"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.logEvent(logEntry{msg: "restarting", level: logInfo})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logEvent blocked on a full inbox")
+	}
+
+	entries := p.log.entries()
+	if len(entries) != 2 || entries[0].msg != "output" || entries[1].msg != "restarting" {
+		t.Fatalf("expected the event after the output already in the inbox, got %#v", entries)
+	}
+	if entries[1].timestamp.IsZero() {
+		t.Fatal("expected the event to be timestamped")
+	}
+}
+
+func TestMergedLogOrdersByTimestamp(t *testing.T) {
+	start := time.Now()
+	api := &process{name: "api"}
+	api.log.add(logEntry{msg: "api 1", timestamp: start})
+	api.log.add(logEntry{msg: "api 2", timestamp: start.Add(2 * time.Millisecond)})
+	worker := &process{name: "worker"}
+	worker.log.add(logEntry{msg: "work 1", level: logError, timestamp: start.Add(time.Millisecond)})
+	group := &process{name: "group", isGroup: true, children: []*process{api, worker}}
+
+	merged := group.mergedLog()
+
+	var msgs []string
+	for _, e := range merged {
+		msgs = append(msgs, stripControlSequences(e.msg))
+	}
+	want := []string{"[api]    api 1", "[worker] work 1", "[api]    api 2"}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected merged log: %#v", msgs)
+	}
+	if merged[1].level != logError {
+		t.Fatalf("expected the merged line to keep its level, got %v", merged[1].level)
+	}
+}
+
+func TestResolvePathUsesConfigDir(t *testing.T) {
+	p := &process{baseDir: "/srv/project"}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "/srv/project"},
+		{"api", "/srv/project/api"},
+		{"../shared/.env", "/srv/shared/.env"},
+		{"/etc/app.env", "/etc/app.env"},
+	}
+	for _, tt := range tests {
+		got, err := p.resolvePath(tt.path)
+		if err != nil {
+			t.Fatalf("resolvePath(%q) returned error: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("resolvePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
 }
@@ -0,0 +1,203 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/input"
+	"github.com/steventhorne/sheepdog/style"
+)
+
+// useSelection sets the processes started on launch, applying the overrides
+// of the selected profile, if any.
+func (m *processList) useSelection(s Selection) error {
+	var overrides map[string]config.Override
+	if s.Profile != "" {
+		profile, ok := m.profiles[s.Profile]
+		if !ok {
+			return fmt.Errorf("unknown profile %q", s.Profile)
+		}
+		if len(s.Only) > 0 {
+			return fmt.Errorf("profile %q can't be combined with a list of processes", s.Profile)
+		}
+		s.Only = profile.Processes
+		overrides = profile.Overrides
+	}
+
+	if err := s.check(m.byName); err != nil {
+		return err
+	}
+
+	m.selection = s
+	m.applyOverrides(overrides)
+	return nil
+}
+
+// applyOverrides replaces the overrides of every process with the given ones.
+func (m *processList) applyOverrides(overrides map[string]config.Override) {
+	for name, p := range m.byName {
+		p.override = overrides[name]
+	}
+}
+
+// overrideChain lists the overrides that apply to p, its own and its groups'.
+func overrideChain(p *process) []config.Override {
+	var chain []config.Override
+	for ; p != nil; p = p.parent {
+		chain = append(chain, p.override)
+	}
+	return chain
+}
+
+// leavesOf returns p itself, or every process below it for a group.
+func leavesOf(p *process) []*process {
+	if !p.isGroup {
+		return []*process{p}
+	}
+	var leaves []*process
+	for _, cp := range p.children {
+		leaves = append(leaves, leavesOf(cp)...)
+	}
+	return leaves
+}
+
+// picked returns the processes the selection starts, along with every
+// process below them.
+func (m *processList) picked(s Selection) ([]*process, map[*process]bool) {
+	var picked []*process
+	for _, p := range m.processes {
+		picked = append(picked, s.pick(p, false)...)
+	}
+
+	leaves := make(map[*process]bool)
+	for _, p := range picked {
+		for _, l := range leavesOf(p) {
+			leaves[l] = true
+		}
+	}
+	return picked, leaves
+}
+
+// switchProfile changes the active profile, or goes back to the autorun
+// processes when name is empty. Processes that aren't part of the new profile
+// are stopped, the ones that are and weren't before are started, and running
+// ones whose overrides changed are restarted.
+func (m *processList) switchProfile(name string) tea.Cmd {
+	next := Selection{Except: m.selection.Except}
+	var overrides map[string]config.Override
+	if name != "" {
+		profile := m.profiles[name]
+		next.Only = profile.Processes
+		next.Profile = name
+		overrides = profile.Overrides
+	}
+
+	_, wasWanted := m.picked(m.selection)
+	before := make(map[*process][]config.Override)
+	for _, l := range m.leaves() {
+		before[l] = overrideChain(l)
+	}
+
+	m.selection = next
+	m.applyOverrides(overrides)
+	next.expand(m.byName)
+
+	picked, wanted := m.picked(next)
+
+	running := func(p *process) bool {
+		s := p.GetStatus()
+		return s.isActive() || s == statusWaiting
+	}
+	changed := func(p *process) bool {
+		return !reflect.DeepEqual(before[p], overrideChain(p))
+	}
+
+	cmds := make([]tea.Cmd, 0)
+	for _, l := range m.leaves() {
+		switch {
+		case !wanted[l] && running(l) && l.status != statusStopping:
+			cmds = append(cmds, l.Kill())
+		case wanted[l] && running(l) && changed(l):
+			cmds = append(cmds, l.Restart())
+		}
+	}
+
+	toStart := func(l *process) bool {
+		return !running(l) && (!wasWanted[l] || changed(l))
+	}
+	for _, p := range picked {
+		leaves := leavesOf(p)
+		// a group that starts as a whole keeps its ordering
+		if !running(p) && !slices.ContainsFunc(leaves, func(l *process) bool { return !toStart(l) }) {
+			cmds = append(cmds, p.Run())
+			continue
+		}
+		for _, l := range leaves {
+			if toStart(l) {
+				cmds = append(cmds, l.Run())
+			}
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// profileNames lists the entries of the profile picker: the autorun
+// processes, shown as an empty name, followed by the profiles by name.
+func (m *processList) profileNames() []string {
+	return append([]string{""}, slices.Sorted(maps.Keys(m.profiles))...)
+}
+
+func (m *processList) openPicker() {
+	m.picking = true
+	m.pickerIndex = slices.Index(m.profileNames(), m.selection.Profile)
+}
+
+// updatePicker handles a key pressed while the profile picker is open.
+func (m *processList) updatePicker(msg tea.KeyMsg) tea.Cmd {
+	names := m.profileNames()
+	switch {
+	case key.Matches(msg, input.DefaultKeyMap.Up):
+		m.pickerIndex = max(0, m.pickerIndex-1)
+	case key.Matches(msg, input.DefaultKeyMap.Down):
+		m.pickerIndex = min(len(names)-1, m.pickerIndex+1)
+	case key.Matches(msg, input.DefaultKeyMap.Enter):
+		m.picking = false
+		if names[m.pickerIndex] != m.selection.Profile {
+			return m.switchProfile(names[m.pickerIndex])
+		}
+	case key.Matches(msg, input.DefaultKeyMap.Profiles), msg.Type == tea.KeyEsc:
+		m.picking = false
+	}
+	return nil
+}
+
+// pickerView renders the profile picker in place of the process list.
+func (m *processList) pickerView() string {
+	var sb strings.Builder
+	for i, name := range m.profileNames() {
+		label := name
+		if name == "" {
+			label = "(autorun)"
+		}
+		if name == m.selection.Profile {
+			label = "● " + label
+		} else {
+			label = "  " + label
+		}
+
+		itemStyle := style.StyleItem
+		if i == m.pickerIndex {
+			itemStyle = itemStyle.Reverse(true)
+		}
+		sb.WriteString(itemStyle.Render(label))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func profileTestList() processList {
+	return newProcessList(config.Config{
+		Processes: []config.ProcessConfig{
+			{Name: "web", GroupType: "parallel", Env: map[string]string{"MODE": "dev"}, Children: []config.ProcessConfig{
+				{Name: "api", Command: []string{"./api", "--port", "8080"}},
+				{Name: "ui", Command: []string{"./ui"}},
+			}},
+			{Name: "db", Command: []string{"pg"}, Autorun: true},
+		},
+		Profiles: map[string]config.Profile{
+			"frontend": {
+				Processes: []string{"web"},
+				Overrides: map[string]config.Override{
+					"web": {Env: map[string]string{"MODE": "mock", "API": "fake"}},
+					"api": {Env: map[string]string{"API": "real"}, Args: []string{"--mock"}},
+				},
+			},
+		},
+	})
+}
+
+func TestUseSelectionProfile(t *testing.T) {
+	pl := profileTestList()
+	if err := pl.useSelection(Selection{Profile: "frontend"}); err != nil {
+		t.Fatalf("useSelection returned error: %v", err)
+	}
+
+	_, wanted := pl.picked(pl.selection)
+	var names []string
+	for _, l := range pl.leaves() {
+		if wanted[l] {
+			names = append(names, l.name)
+		}
+	}
+	if !slices.Equal(names, []string{"api", "ui"}) {
+		t.Fatalf("expected the profile's processes to be picked, got %v", names)
+	}
+
+	api := pl.byName["api"]
+	if got := api.commandLine(); !slices.Equal(got, []string{"./api", "--mock"}) {
+		t.Fatalf("unexpected command line: %v", got)
+	}
+	env, err := api.environ()
+	if err != nil {
+		t.Fatalf("environ returned error: %v", err)
+	}
+	for _, kv := range []string{"MODE=mock", "API=real"} {
+		if !slices.Contains(env, kv) {
+			t.Errorf("expected %s in the environment", kv)
+		}
+	}
+
+	if got := pl.byName["ui"].commandLine(); !slices.Equal(got, []string{"./ui"}) {
+		t.Fatalf("expected ui to keep its command, got %v", got)
+	}
+}
+
+func TestUseSelectionProfileErrors(t *testing.T) {
+	pl := profileTestList()
+	if err := pl.useSelection(Selection{Profile: "backend"}); err == nil {
+		t.Fatal("expected an error for an unknown profile, got nil")
+	}
+	if err := pl.useSelection(Selection{Profile: "frontend", Only: []string{"db"}}); err == nil {
+		t.Fatal("expected an error for a profile combined with processes, got nil")
+	}
+}
@@ -16,13 +16,27 @@ type model struct {
 	quitting  bool
 }
 
-func NewModel(config config.Config, version string) model {
+// NewModel creates the TUI model. The selection overrides which processes are
+// started on launch; an empty one starts the autorun processes.
+func NewModel(config config.Config, selection Selection, version string) (model, error) {
 	m := model{
 		processes: newProcessList(config),
 	}
 	m.processes.version = version
 
-	return m
+	if err := m.processes.useSelection(selection); err != nil {
+		return m, err
+	}
+	m.processes.selection.expand(m.processes.byName)
+
+	return m, nil
+}
+
+// SetProgram hands the model the program it runs in, which must be done
+// before the program is run. Processes wake it up with their output and
+// status changes as they happen.
+func (m model) SetProgram(p *tea.Program) {
+	m.processes.setProgram(p)
 }
 
 func (m model) Init() tea.Cmd {
@@ -0,0 +1,56 @@
+//go:build !windows
+
+package model
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// ptySupported reports whether commands can be run in a pseudo-terminal.
+const ptySupported = true
+
+// openPty opens a pseudo-terminal of the given size. The command runs in
+// tty, and its output is read from ptmx.
+func openPty(cols, rows int) (ptmx, tty *os.File, err error) {
+	ptmx, tty, err = pty.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := resizePty(ptmx, cols, rows); err != nil {
+		ptmx.Close()
+		tty.Close()
+		return nil, nil, err
+	}
+	return ptmx, tty, nil
+}
+
+// resizePty tells the command in the pseudo-terminal its new size.
+func resizePty(ptmx *os.File, cols, rows int) error {
+	return pty.Setsize(ptmx, &pty.Winsize{Cols: uint16(max(cols, 1)), Rows: uint16(max(rows, 1))})
+}
+
+// ptyOutput returns the reader for the output of a command in a
+// pseudo-terminal.
+func ptyOutput(ptmx *os.File) io.ReadCloser {
+	return ptyReader{ptmx}
+}
+
+// ptyReader reads from the master side of a pseudo-terminal. Linux reports
+// EIO rather than EOF once nothing has the terminal open anymore, which is
+// how the output of every command ends.
+type ptyReader struct {
+	*os.File
+}
+
+func (r ptyReader) Read(p []byte) (int, error) {
+	n, err := r.File.Read(p)
+	if errors.Is(err, syscall.EIO) {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package model
+
+import (
+	"io"
+	"testing"
+)
+
+func TestPtyOutputEndsWithEOF(t *testing.T) {
+	ptmx, tty, err := openPty(100, 30)
+	if err != nil {
+		t.Skipf("no pseudo-terminals available: %v", err)
+	}
+	r := ptyOutput(ptmx)
+	defer r.Close()
+
+	if _, err := tty.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	tty.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("expected the output to end without an error, got %v", err)
+	}
+	// the terminal turns the newline into CRLF
+	if string(data) != "hello\r\n" {
+		t.Fatalf("unexpected output: %q", data)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package model
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ptySupported reports whether commands can be run in a pseudo-terminal.
+const ptySupported = false
+
+func openPty(cols, rows int) (ptmx, tty *os.File, err error) {
+	return nil, nil, errors.New("pseudo-terminals are not supported on Windows")
+}
+
+func resizePty(ptmx *os.File, cols, rows int) error {
+	return nil
+}
+
+func ptyOutput(ptmx *os.File) io.ReadCloser {
+	return ptmx
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func newRestartProcess(t *testing.T, conf config.ProcessConfig) *process {
+	t.Helper()
+	conf.Name = "api"
+	conf.Command = []string{"api"}
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{conf}})
+	return pl.byName["api"]
+}
+
+func TestScheduleRestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy        string
+		status        processStatus
+		stopRequested bool
+		restart       bool
+	}{
+		{"", statusErrored, false, false},
+		{restartNever, statusErrored, false, false},
+		{restartNever, statusExited, false, false},
+		{restartOnFailure, statusErrored, false, true},
+		{restartOnFailure, statusExited, false, false},
+		{restartOnFailure, statusErrored, true, false},
+		{restartAlways, statusErrored, false, true},
+		{restartAlways, statusExited, false, true},
+		{restartAlways, statusExited, true, false},
+	}
+
+	for _, tt := range tests {
+		p := newRestartProcess(t, config.ProcessConfig{Restart: tt.policy})
+		p.status = tt.status
+		p.stopRequested = tt.stopRequested
+
+		cmd := p.scheduleRestart()
+		if restarted := cmd != nil && !p.restartAt.IsZero(); restarted != tt.restart {
+			t.Errorf("policy %q, status %v, stop requested %v: restarted = %v, want %v",
+				tt.policy, tt.status, tt.stopRequested, restarted, tt.restart)
+		}
+	}
+}
+
+func TestScheduleRestartGivesUpAfterMaxRestarts(t *testing.T) {
+	p := newRestartProcess(t, config.ProcessConfig{Restart: restartAlways, MaxRestarts: 2})
+	p.status = statusErrored
+
+	for range 2 {
+		if p.scheduleRestart() == nil {
+			t.Fatal("expected a restart")
+		}
+		p.restartAt = time.Time{}
+	}
+	if p.scheduleRestart() != nil || !p.restartAt.IsZero() {
+		t.Fatal("expected no restart after maxRestarts")
+	}
+}
+
+func TestRestartBackoffDoublesUpToMax(t *testing.T) {
+	p := newRestartProcess(t, config.ProcessConfig{
+		Restart:           restartAlways,
+		RestartBackoff:    config.Duration(time.Second),
+		RestartMaxBackoff: config.Duration(10 * time.Second),
+	})
+
+	want := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	}
+	for i, backoff := range want {
+		p.restartCount = i
+		if got := p.restartDelay(); got != backoff {
+			t.Errorf("restart %d: delay = %s, want %s", i+1, got, backoff)
+		}
+	}
+}
+
+func TestStableRunResetsRestartCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		uptime time.Duration
+		count  int
+	}{
+		{"crash right after starting", time.Second, 4},
+		{"crash after a stable run", restartResetAfter, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newRestartProcess(t, config.ProcessConfig{Restart: restartOnFailure})
+			ended := time.Now()
+			p.status = statusReady
+			p.restartCount = 3
+			p.run = runInfo{started: ended.Add(-tt.uptime), count: 4}
+			p.exitCh <- exitInfo{ended: ended, exitCode: 1}
+			p.statusCh <- statusErrored
+
+			p.Update(processMsg{id: p.id})
+			if p.restartCount != tt.count {
+				t.Fatalf("expected restart %d to be scheduled, got %d", tt.count, p.restartCount)
+			}
+			if tt.count == 1 && time.Until(p.restartAt) > p.restartBackoff {
+				t.Fatalf("expected the initial backoff, restarting in %s", time.Until(p.restartAt))
+			}
+		})
+	}
+}
+
+func TestKillCancelsPendingRestart(t *testing.T) {
+	// the restart tick is due right away
+	p := newRestartProcess(t, config.ProcessConfig{Restart: restartOnFailure, RestartBackoff: 1})
+	p.status = statusErrored
+	if p.scheduleRestart() == nil {
+		t.Fatal("expected a restart")
+	}
+	gen := p.restartGen
+
+	p.Kill()
+	if !p.restartAt.IsZero() || !p.stopRequested {
+		t.Fatalf("expected the restart to be called off, restarting at %v", p.restartAt)
+	}
+
+	logged := len(p.log.entries())
+	p.Update(restartMsg{id: p.id, gen: gen})
+	if p.status != statusErrored || len(p.log.entries()) != logged {
+		t.Fatalf("expected the process not to be started, got %v and %#v", p.status, p.log.entries()[logged:])
+	}
+}
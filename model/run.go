@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runInfo describes the current or last run of a process's command.
+type runInfo struct {
+	pid     int
+	started time.Time
+	// ended is zero while the command is still running.
+	ended time.Time
+	// exitCode is -1 when the command was terminated by a signal, which
+	// signal then names.
+	exitCode int
+	signal   string
+	// count is the number of times the command was started.
+	count int
+}
+
+// exitInfo is how a run ended, as reported by the goroutine waiting on it.
+type exitInfo struct {
+	ended    time.Time
+	exitCode int
+	signal   string
+}
+
+// running reports whether the command has been started and not exited.
+func (r runInfo) running() bool {
+	return r.count > 0 && r.ended.IsZero()
+}
+
+// uptime is how long the command has been running, or ran for.
+func (r runInfo) uptime(now time.Time) time.Duration {
+	end := r.ended
+	if end.IsZero() {
+		end = now
+	}
+	return end.Sub(r.started).Round(time.Second)
+}
+
+// result describes how the run ended: its exit code or the signal that
+// terminated it.
+func (r runInfo) result() string {
+	if r.signal != "" {
+		return r.signal
+	}
+	if r.exitCode < 0 {
+		return "killed"
+	}
+	return fmt.Sprintf("exit %d", r.exitCode)
+}
+
+// details summarizes the run for the detail header, or returns "" if the
+// command never ran.
+func (r runInfo) details(now time.Time) string {
+	if r.count == 0 {
+		return ""
+	}
+
+	parts := []string{fmt.Sprintf("pid %d", r.pid)}
+	if r.running() {
+		parts = append(parts,
+			"started "+r.started.Format(time.TimeOnly),
+			"up "+r.uptime(now).String(),
+		)
+	} else {
+		parts = append(parts,
+			r.result(),
+			fmt.Sprintf("%s–%s (%s)", r.started.Format(time.TimeOnly), r.ended.Format(time.TimeOnly), r.uptime(now)),
+		)
+	}
+	parts = append(parts, fmt.Sprintf("run %d", r.count))
+	return strings.Join(parts, " · ")
+}
+
+// short is the uptime of a running command, or how the last run ended, for
+// the process list.
+func (r runInfo) short(now time.Time) string {
+	switch {
+	case r.count == 0:
+		return ""
+	case r.running():
+		return r.uptime(now).String()
+	default:
+		return r.result()
+	}
+}
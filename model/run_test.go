@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunInfoDetails(t *testing.T) {
+	started := time.Date(2024, 5, 1, 14, 1, 2, 0, time.Local)
+	now := started.Add(3*time.Minute + 12*time.Second)
+
+	tests := []struct {
+		name    string
+		run     runInfo
+		details string
+		short   string
+	}{
+		{"never ran", runInfo{}, "", ""},
+		{
+			"running",
+			runInfo{pid: 4242, started: started, count: 1},
+			"pid 4242 · started 14:01:02 · up 3m12s · run 1",
+			"3m12s",
+		},
+		{
+			"exited",
+			runInfo{pid: 4242, started: started, ended: now, exitCode: 1, count: 2},
+			"pid 4242 · exit 1 · 14:01:02–14:04:14 (3m12s) · run 2",
+			"exit 1",
+		},
+		{
+			"signaled",
+			runInfo{pid: 4242, started: started, ended: now, exitCode: -1, signal: "SIGTERM", count: 3},
+			"pid 4242 · SIGTERM · 14:01:02–14:04:14 (3m12s) · run 3",
+			"SIGTERM",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run.details(now); got != tt.details {
+				t.Errorf("details() = %q, want %q", got, tt.details)
+			}
+			if got := tt.run.short(now); got != tt.short {
+				t.Errorf("short() = %q, want %q", got, tt.short)
+			}
+		})
+	}
+}
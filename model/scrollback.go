@@ -0,0 +1,202 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultScrollback is the number of log lines kept in memory per process
+// when the config doesn't set one. Older lines are spilled to disk.
+const defaultScrollback = 1024
+
+// scrollback is the log of a process. The most recent lines are kept in a
+// ring buffer; the ones it pushes out are appended to a temp file, where
+// they can still be read back to be shown and searched.
+type scrollback struct {
+	// ring holds count lines, the oldest at start.
+	ring  []logEntry
+	start int
+	count int
+	// added is the number of lines ever added.
+	added int
+
+	spill     *os.File
+	spillW    *bufio.Writer
+	spillSize int64
+	// spilled is the number of lines in the spill file, and lost the
+	// number that couldn't be written to it.
+	spilled int
+	lost    int
+	err     error
+
+	// history caches the spilled lines while they are being shown, read
+	// from the spill file up to historySize.
+	history     []logEntry
+	historySize int64
+}
+
+func newScrollback(size int) scrollback {
+	return scrollback{ring: make([]logEntry, max(size, 1))}
+}
+
+// add appends entry, spilling the oldest line to disk once the ring is full.
+func (s *scrollback) add(entry logEntry) {
+	if s.ring == nil {
+		s.ring = make([]logEntry, defaultScrollback)
+	}
+	s.added++
+	if s.count < len(s.ring) {
+		s.ring[(s.start+s.count)%len(s.ring)] = entry
+		s.count++
+		return
+	}
+
+	oldest := s.ring[s.start]
+	s.ring[s.start] = entry
+	s.start = (s.start + 1) % len(s.ring)
+	s.spillEntry(oldest)
+}
+
+// len is the number of lines in memory.
+func (s *scrollback) len() int {
+	return s.count
+}
+
+// entries returns the lines in memory, oldest first.
+func (s *scrollback) entries() []logEntry {
+	entries := make([]logEntry, s.count)
+	for i := range entries {
+		entries[i] = s.ring[(s.start+i)%len(s.ring)]
+	}
+	return entries
+}
+
+// since returns the lines in memory from the nth line ever added on, oldest
+// first.
+func (s *scrollback) since(n int) []logEntry {
+	skip := max(0, n-(s.added-s.count))
+	entries := make([]logEntry, 0, max(0, s.count-skip))
+	for i := skip; i < s.count; i++ {
+		entries = append(entries, s.ring[(s.start+i)%len(s.ring)])
+	}
+	return entries
+}
+
+// tail returns the last n lines, reading them from disk when there aren't
+// enough in memory.
+func (s *scrollback) tail(n int) []logEntry {
+	entries := s.entries()
+	if n <= len(entries) || s.spilled == 0 {
+		return entries[max(0, len(entries)-n):]
+	}
+
+	older, _, err := s.readSpill(0)
+	if err != nil {
+		return entries
+	}
+	entries = append(older, entries...)
+	return entries[max(0, len(entries)-n):]
+}
+
+// spillEntry appends entry to the spill file, creating it on first use.
+func (s *scrollback) spillEntry(entry logEntry) {
+	if s.err != nil {
+		s.lost++
+		return
+	}
+
+	if s.spill == nil {
+		f, err := os.CreateTemp("", "sheepdog-*.log")
+		if err != nil {
+			s.err = err
+			s.lost++
+			return
+		}
+		// the open file can still be used, and is cleaned up however
+		// sheepdog exits. Windows doesn't allow this, so the file stays
+		// in the temp directory there.
+		_ = os.Remove(f.Name())
+		s.spill = f
+		s.spillW = bufio.NewWriter(f)
+	}
+
+	msg := strings.ReplaceAll(entry.msg, "\n", " ")
+	n, err := fmt.Fprintf(s.spillW, "%d\t%s\t%s\n", entry.timestamp.UnixNano(), entry.level, msg)
+	s.spillSize += int64(n)
+	if err != nil {
+		s.err = err
+		s.lost++
+		return
+	}
+	s.spilled++
+}
+
+// readSpill reads the spilled lines from offset to the end of the file,
+// returning them along with the new end.
+func (s *scrollback) readSpill(offset int64) ([]logEntry, int64, error) {
+	if s.spill == nil {
+		return nil, offset, nil
+	}
+	if err := s.spillW.Flush(); err != nil {
+		return nil, offset, err
+	}
+
+	var entries []logEntry
+	scanner := bufio.NewScanner(io.NewSectionReader(s.spill, offset, s.spillSize-offset))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineBytes+64)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, logEntry{
+			msg:       parts[2],
+			level:     logLevel(parts[1]),
+			timestamp: time.Unix(0, nanos),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, offset, err
+	}
+	return entries, s.spillSize, nil
+}
+
+// loadHistory returns every spilled line, reading only the ones spilled
+// since the last call from disk.
+func (s *scrollback) loadHistory() ([]logEntry, error) {
+	entries, size, err := s.readSpill(s.historySize)
+	if err != nil {
+		return s.history, err
+	}
+	s.history = append(s.history, entries...)
+	s.historySize = size
+	return s.history, nil
+}
+
+// dropHistory frees the spilled lines read by loadHistory.
+func (s *scrollback) dropHistory() {
+	s.history = nil
+	s.historySize = 0
+}
+
+// summary describes the lines that aren't in memory, or returns "" if there
+// are none.
+func (s *scrollback) summary() string {
+	var parts []string
+	if s.spilled > 0 {
+		parts = append(parts, fmt.Sprintf("%d older lines on disk", s.spilled))
+	}
+	if s.lost > 0 {
+		parts = append(parts, fmt.Sprintf("%d older lines lost (%v)", s.lost, s.err))
+	}
+	return strings.Join(parts, ", ")
+}
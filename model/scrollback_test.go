@@ -0,0 +1,88 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrollbackMsgs(entries []logEntry) string {
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e.msg)
+	}
+	return strings.Join(msgs, "|")
+}
+
+func TestScrollbackKeepsNewestLinesInOrder(t *testing.T) {
+	s := newScrollback(3)
+	for i := range 5 {
+		s.add(logEntry{msg: fmt.Sprint(i)})
+	}
+	t.Cleanup(func() { s.spill.Close() })
+
+	if got := scrollbackMsgs(s.entries()); got != "2|3|4" {
+		t.Fatalf("unexpected lines in memory: %q", got)
+	}
+	if s.len() != 3 || s.spilled != 2 {
+		t.Fatalf("expected 3 lines in memory and 2 on disk, got %d and %d", s.len(), s.spilled)
+	}
+	if got := s.summary(); got != "2 older lines on disk" {
+		t.Fatalf("unexpected summary: %q", got)
+	}
+}
+
+func TestScrollbackReadsSpilledLinesBack(t *testing.T) {
+	start := time.Now()
+	s := newScrollback(2)
+	s.add(logEntry{msg: "one", level: logError, timestamp: start})
+	s.add(logEntry{msg: "two\nlines", timestamp: start})
+	s.add(logEntry{msg: "three", timestamp: start})
+	s.add(logEntry{msg: "four", timestamp: start})
+	t.Cleanup(func() { s.spill.Close() })
+
+	history, err := s.loadHistory()
+	if err != nil {
+		t.Fatalf("loadHistory returned error: %v", err)
+	}
+	if got := scrollbackMsgs(history); got != "one|two lines" {
+		t.Fatalf("unexpected history: %q", got)
+	}
+	if history[0].level != logError || !history[0].timestamp.Equal(start) {
+		t.Fatalf("expected the level and timestamp to be kept, got %#v", history[0])
+	}
+
+	// only the lines spilled since are read on the next load
+	s.add(logEntry{msg: "five", timestamp: start})
+	history, err = s.loadHistory()
+	if err != nil {
+		t.Fatalf("loadHistory returned error: %v", err)
+	}
+	if got := scrollbackMsgs(history); got != "one|two lines|three" {
+		t.Fatalf("unexpected history after another spill: %q", got)
+	}
+
+	s.dropHistory()
+	if s.history != nil || s.historySize != 0 {
+		t.Fatal("expected dropHistory to free the loaded lines")
+	}
+}
+
+func TestScrollbackTailReadsFromDisk(t *testing.T) {
+	s := newScrollback(2)
+	for i := range 5 {
+		s.add(logEntry{msg: fmt.Sprint(i)})
+	}
+	t.Cleanup(func() { s.spill.Close() })
+
+	if got := scrollbackMsgs(s.tail(1)); got != "4" {
+		t.Fatalf("unexpected tail from memory: %q", got)
+	}
+	if got := scrollbackMsgs(s.tail(4)); got != "1|2|3|4" {
+		t.Fatalf("unexpected tail from disk: %q", got)
+	}
+	if got := scrollbackMsgs(s.tail(10)); got != "0|1|2|3|4" {
+		t.Fatalf("unexpected tail of everything: %q", got)
+	}
+}
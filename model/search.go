@@ -0,0 +1,221 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/steventhorne/sheepdog/style"
+)
+
+type promptMode int
+
+const (
+	promptNone promptMode = iota
+	promptSearch
+	promptFilter
+	// promptInput is a line of input for the process's command.
+	promptInput
+)
+
+// prefix is shown in front of the text typed into the prompt.
+func (p promptMode) prefix() string {
+	switch p {
+	case promptSearch:
+		return "/"
+	case promptFilter:
+		return "&"
+	case promptInput:
+		return "> "
+	default:
+		return ""
+	}
+}
+
+// logSearch narrows and highlights the lines shown in a process's viewport.
+type logSearch struct {
+	// pattern is highlighted in the visible lines, which n/N jump between.
+	pattern *regexp.Regexp
+	// filter hides the lines it doesn't match, or the ones it does when
+	// invert is set.
+	filter     *regexp.Regexp
+	invert     bool
+	stderrOnly bool
+
+	// matches holds the viewport line of every line pattern matched when
+	// the viewport was last loaded, and current the one last jumped to.
+	matches []int
+	current int
+}
+
+// active reports whether the search changes what the viewport shows.
+func (s *logSearch) active() bool {
+	return s.pattern != nil || s.filter != nil || s.stderrOnly
+}
+
+// setFilter parses a filter typed into the prompt. A leading "!" inverts it.
+func (s *logSearch) setFilter(text string) error {
+	// the lines the matches were found on move once the filter changes
+	s.current = -1
+
+	invert := strings.HasPrefix(text, "!")
+	text = strings.TrimPrefix(text, "!")
+	if text == "" {
+		s.filter = nil
+		s.invert = false
+		return nil
+	}
+
+	rg, err := regexp.Compile(text)
+	if err != nil {
+		return err
+	}
+	s.filter = rg
+	s.invert = invert
+	return nil
+}
+
+// setPattern parses a search typed into the prompt.
+func (s *logSearch) setPattern(text string) error {
+	s.matches = nil
+	s.current = -1
+	if text == "" {
+		s.pattern = nil
+		return nil
+	}
+
+	rg, err := regexp.Compile(text)
+	if err != nil {
+		return err
+	}
+	s.pattern = rg
+	return nil
+}
+
+// visible reports whether entry passes the stderr toggle and the filter.
+func (s *logSearch) visible(entry logEntry) bool {
+	if s.stderrOnly && entry.level != logError {
+		return false
+	}
+	if s.filter != nil {
+		return s.filter.MatchString(stripControlSequences(entry.msg)) != s.invert
+	}
+	return true
+}
+
+// highlight marks every match of the search pattern in line. Matching lines
+// lose their own colors so that the highlight can't land inside an escape
+// sequence.
+func (s *logSearch) highlight(line string) (string, bool) {
+	if s.pattern == nil {
+		return line, false
+	}
+
+	clean := stripControlSequences(line)
+	if !s.pattern.MatchString(clean) {
+		return line, false
+	}
+	return s.pattern.ReplaceAllStringFunc(clean, func(match string) string {
+		return style.StyleSearchMatch.Render(match)
+	}), true
+}
+
+// render lays out the visible lines at the given width and records the
+// viewport line of every search match.
+func (s *logSearch) render(entries []logEntry, width int) string {
+	s.matches = s.matches[:0]
+
+	wrap := lipgloss.NewStyle().Width(width)
+	sb := &strings.Builder{}
+	lines := 0
+	for _, entry := range entries {
+		if !s.visible(entry) {
+			continue
+		}
+
+		msg, matched := s.highlight(entry.msg)
+		if matched {
+			s.matches = append(s.matches, lines)
+		}
+
+		rendered := wrap.Render(msg)
+		sb.WriteString(rendered)
+		sb.WriteString("\n")
+		lines += strings.Count(rendered, "\n") + 1
+	}
+
+	if s.current >= len(s.matches) {
+		s.current = len(s.matches) - 1
+	}
+	return sb.String()
+}
+
+// next moves to the match after the current one, or to the first match at or
+// below line when there is none yet, wrapping around to the top. It returns
+// the match's viewport line, or -1 when there are no matches.
+func (s *logSearch) next(line int) int {
+	if len(s.matches) == 0 {
+		return -1
+	}
+
+	if s.current >= 0 {
+		s.current = (s.current + 1) % len(s.matches)
+		return s.matches[s.current]
+	}
+
+	s.current = 0
+	for i, l := range s.matches {
+		if l >= line {
+			s.current = i
+			break
+		}
+	}
+	return s.matches[s.current]
+}
+
+// prev moves to the match before the current one, or to the last match above
+// line when there is none yet, wrapping around to the bottom. It returns the
+// match's viewport line, or -1 when there are no matches.
+func (s *logSearch) prev(line int) int {
+	if len(s.matches) == 0 {
+		return -1
+	}
+
+	if s.current >= 0 {
+		s.current = (s.current - 1 + len(s.matches)) % len(s.matches)
+		return s.matches[s.current]
+	}
+
+	s.current = len(s.matches) - 1
+	for i := len(s.matches) - 1; i >= 0; i-- {
+		if s.matches[i] < line {
+			s.current = i
+			break
+		}
+	}
+	return s.matches[s.current]
+}
+
+// String summarizes the search for the viewport header.
+func (s *logSearch) String() string {
+	var parts []string
+	if s.pattern != nil {
+		if len(s.matches) == 0 {
+			parts = append(parts, fmt.Sprintf("[/%s no matches]", s.pattern))
+		} else {
+			parts = append(parts, fmt.Sprintf("[/%s %d/%d]", s.pattern, s.current+1, len(s.matches)))
+		}
+	}
+	if s.filter != nil {
+		if s.invert {
+			parts = append(parts, fmt.Sprintf("[&!%s]", s.filter))
+		} else {
+			parts = append(parts, fmt.Sprintf("[&%s]", s.filter))
+		}
+	}
+	if s.stderrOnly {
+		parts = append(parts, "[stderr]")
+	}
+	return strings.Join(parts, "  ")
+}
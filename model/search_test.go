@@ -0,0 +1,81 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogSearchFilter(t *testing.T) {
+	entries := []logEntry{
+		{msg: "GET /health 200", level: logInfo},
+		{msg: "GET /users 500", level: logInfo},
+		{msg: "panic: nil map", level: logError},
+	}
+
+	visible := func(s *logSearch) []string {
+		var msgs []string
+		for _, e := range entries {
+			if s.visible(e) {
+				msgs = append(msgs, e.msg)
+			}
+		}
+		return msgs
+	}
+
+	s := logSearch{current: -1}
+	if err := s.setFilter("^GET"); err != nil {
+		t.Fatalf("setFilter returned error: %v", err)
+	}
+	if got := visible(&s); len(got) != 2 {
+		t.Fatalf("unexpected filtered lines: %#v", got)
+	}
+
+	if err := s.setFilter("!/health"); err != nil {
+		t.Fatalf("setFilter returned error: %v", err)
+	}
+	if got := visible(&s); len(got) != 2 || got[0] != "GET /users 500" {
+		t.Fatalf("unexpected inverted lines: %#v", got)
+	}
+
+	s.stderrOnly = true
+	if got := visible(&s); len(got) != 1 || got[0] != "panic: nil map" {
+		t.Fatalf("unexpected stderr lines: %#v", got)
+	}
+
+	if err := s.setFilter("("); err == nil {
+		t.Fatal("expected error for invalid regexp, got nil")
+	}
+}
+
+func TestLogSearchMatches(t *testing.T) {
+	entries := []logEntry{
+		{msg: "starting"},
+		{msg: strings.Repeat("x", 8) + " error"},
+		{msg: "ok"},
+		{msg: "error again"},
+	}
+
+	s := logSearch{current: -1}
+	if err := s.setPattern("error"); err != nil {
+		t.Fatalf("setPattern returned error: %v", err)
+	}
+	s.render(entries, 10)
+
+	// the second entry wraps onto two lines, pushing the last match down
+	if len(s.matches) != 2 || s.matches[0] != 1 || s.matches[1] != 4 {
+		t.Fatalf("unexpected match lines: %#v", s.matches)
+	}
+
+	if line := s.next(2); line != 4 {
+		t.Fatalf("expected next match on line 4, got %d", line)
+	}
+	if line := s.next(4); line != 1 {
+		t.Fatalf("expected next match to wrap to line 1, got %d", line)
+	}
+	if line := s.prev(1); line != 4 {
+		t.Fatalf("expected previous match to wrap to line 4, got %d", line)
+	}
+	if got := s.String(); got != "[/error 2/2]" {
+		t.Fatalf("unexpected summary: %q", got)
+	}
+}
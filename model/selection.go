@@ -0,0 +1,118 @@
+package model
+
+import (
+	"fmt"
+	"path"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Selection overrides which processes are started on launch. Both lists hold
+// process names or glob patterns such as "svc-*"; a group stands for all of
+// its children.
+type Selection struct {
+	// Profile names the profile from the config whose processes are
+	// started, instead of listing them in Only.
+	Profile string
+	// Only starts the matching processes instead of the autorun ones.
+	Only []string
+	// Except keeps the matching processes from being started.
+	Except []string
+}
+
+// check makes sure every pattern is valid and matches at least one process,
+// so that typos don't go unnoticed.
+func (s Selection) check(byName map[string]*process) error {
+	for _, pattern := range append(append([]string{}, s.Only...), s.Except...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+
+		found := false
+		for name := range byName {
+			if ok, _ := path.Match(pattern, name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown process %q", pattern)
+		}
+	}
+	return nil
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// includes reports whether p itself is selected to start: by name when Only
+// is given, otherwise by its autorun setting.
+func (s Selection) includes(p *process) bool {
+	if len(s.Only) > 0 {
+		return matchesAny(s.Only, p.name)
+	}
+	return p.autorun
+}
+
+// excludesWithin reports whether p or anything below it is excluded.
+func (s Selection) excludesWithin(p *process) bool {
+	if matchesAny(s.Except, p.name) {
+		return true
+	}
+	for _, cp := range p.children {
+		if s.excludesWithin(cp) {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the processes to run to start everything selected under p.
+// A selected group is run as a whole so that it keeps its ordering, unless
+// some of its children are excluded, in which case the rest are picked one
+// by one.
+func (s Selection) pick(p *process, selected bool) []*process {
+	if matchesAny(s.Except, p.name) {
+		return nil
+	}
+
+	selected = selected || s.includes(p)
+	if selected && !s.excludesWithin(p) {
+		return []*process{p}
+	}
+
+	var picked []*process
+	for _, cp := range p.children {
+		picked = append(picked, s.pick(cp, selected)...)
+	}
+	return picked
+}
+
+// run starts the processes picked under p.
+func (s Selection) run(p *process) tea.Cmd {
+	picked := s.pick(p, false)
+	cmds := make([]tea.Cmd, 0, len(picked))
+	for _, pp := range picked {
+		cmds = append(cmds, pp.Run())
+	}
+	return tea.Batch(cmds...)
+}
+
+// expand opens every group above a process picked by name, so that it can be
+// seen in the list.
+func (s Selection) expand(byName map[string]*process) {
+	for name, p := range byName {
+		if !matchesAny(s.Only, name) {
+			continue
+		}
+		for a := p.parent; a != nil; a = a.parent {
+			a.isFocused = true
+		}
+	}
+}
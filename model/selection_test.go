@@ -0,0 +1,79 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func selectionTestList() processList {
+	return newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "db", Command: []string{"db"}, Autorun: true},
+		{Name: "services", GroupType: "parallel", Children: []config.ProcessConfig{
+			{Name: "svc-api", Command: []string{"api"}},
+			{Name: "svc-worker", Command: []string{"worker"}},
+		}},
+		{Name: "docs", Command: []string{"docs"}, Autorun: true},
+	}})
+}
+
+func TestSelectionPick(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection Selection
+		want      []string
+	}{
+		{"autorun", Selection{}, []string{"db", "docs"}},
+		{"only", Selection{Only: []string{"svc-api", "docs"}}, []string{"svc-api", "docs"}},
+		{"glob", Selection{Only: []string{"svc-*"}}, []string{"svc-api", "svc-worker"}},
+		{"group", Selection{Only: []string{"services"}}, []string{"services"}},
+		{"except", Selection{Except: []string{"docs"}}, []string{"db"}},
+		{"except within group", Selection{Only: []string{"services"}, Except: []string{"*-worker"}}, []string{"svc-api"}},
+		{"except group", Selection{Only: []string{"*"}, Except: []string{"services"}}, []string{"db", "docs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := selectionTestList()
+			if err := tt.selection.check(pl.byName); err != nil {
+				t.Fatalf("check returned error: %v", err)
+			}
+
+			var got []string
+			for _, p := range pl.processes {
+				for _, picked := range tt.selection.pick(p, false) {
+					got = append(got, picked.name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectionCheck(t *testing.T) {
+	pl := selectionTestList()
+	if err := (Selection{Only: []string{"svc-api"}, Except: []string{"nope-*"}}).check(pl.byName); err == nil {
+		t.Fatal("expected an error for a pattern that matches nothing, got nil")
+	}
+	if err := (Selection{Only: []string{"[svc"}}).check(pl.byName); err == nil {
+		t.Fatal("expected an error for an invalid pattern, got nil")
+	}
+}
+
+func TestSelectionExpandsAncestors(t *testing.T) {
+	pl := selectionTestList()
+	services := pl.byName["services"]
+
+	Selection{Only: []string{"docs"}}.expand(pl.byName)
+	if services.isFocused {
+		t.Fatal("expected services to stay collapsed")
+	}
+
+	Selection{Only: []string{"svc-api"}}.expand(pl.byName)
+	if !services.isFocused {
+		t.Fatal("expected services to be expanded")
+	}
+}
@@ -0,0 +1,315 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pen is the SGR state text is written with: the attributes that are on, as
+// their SGR numbers 1 to 9, and the colors as the parameters that set them.
+type pen struct {
+	attrs uint16
+	fg    string
+	bg    string
+	// ul is the underline color.
+	ul string
+}
+
+// apply updates the pen with the parameters of an SGR sequence.
+func (p *pen) apply(params string) {
+	tokens := strings.Split(params, ";")
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		// colors and underline styles can also be written with colons, in
+		// which case the whole color is a single token
+		switch {
+		case strings.HasPrefix(t, "38:"):
+			p.fg = t
+			continue
+		case strings.HasPrefix(t, "48:"):
+			p.bg = t
+			continue
+		case strings.HasPrefix(t, "58:"):
+			p.ul = t
+			continue
+		case strings.HasPrefix(t, "4:"):
+			if t == "4:0" {
+				p.attrs &^= 1 << 4
+			} else {
+				p.attrs |= 1 << 4
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(t)
+		if t != "" && err != nil {
+			continue
+		}
+		switch {
+		case n == 0:
+			*p = pen{}
+		case n >= 1 && n <= 9:
+			p.attrs |= 1 << n
+		case n == 22:
+			p.attrs &^= 1<<1 | 1<<2
+		case n >= 23 && n <= 29:
+			p.attrs &^= 1 << (n - 20)
+		case n >= 30 && n <= 37, n >= 90 && n <= 97:
+			p.fg = t
+		case n == 39:
+			p.fg = ""
+		case n >= 40 && n <= 47, n >= 100 && n <= 107:
+			p.bg = t
+		case n == 49:
+			p.bg = ""
+		case n == 59:
+			p.ul = ""
+		case n == 38 || n == 48 || n == 58:
+			// 5;n picks from the 256 color palette, 2;r;g;b is a true color
+			end := i + 1
+			if i+1 < len(tokens) && tokens[i+1] == "5" {
+				end = i + 3
+			} else if i+1 < len(tokens) && tokens[i+1] == "2" {
+				end = i + 5
+			}
+			if end > len(tokens) {
+				return
+			}
+			color := strings.Join(tokens[i:end], ";")
+			i = end - 1
+			switch n {
+			case 38:
+				p.fg = color
+			case 48:
+				p.bg = color
+			default:
+				p.ul = color
+			}
+		}
+	}
+}
+
+// sgr returns the sequence that sets the pen after a reset, or "" for the
+// default pen.
+func (p pen) sgr() string {
+	var params []string
+	for n := 1; n <= 9; n++ {
+		if p.attrs&(1<<n) != 0 {
+			params = append(params, strconv.Itoa(n))
+		}
+	}
+	for _, color := range []string{p.fg, p.bg, p.ul} {
+		if color != "" {
+			params = append(params, color)
+		}
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// cell is a character on a line and the pen it was written with.
+type cell struct {
+	s   string
+	pen pen
+}
+
+var blankCell = cell{s: " "}
+
+// term is a minimal terminal that output is run through one line at a time.
+// It resolves what a real terminal would do within a line, such as
+// progress bars that redraw themselves after a carriage return or erase the
+// line, and keeps the SGR state from one line to the next. Every line it
+// returns sets its own styles and ends with a reset, so that a style left
+// open can't bleed into the next line or the rest of the UI. A wide
+// character takes up a single cell.
+//
+// Moving the cursor to other lines, or anywhere else on the screen, isn't
+// supported; those sequences are dropped along with everything else that
+// isn't text or one of the sequences above.
+type term struct {
+	pen   pen
+	cells []cell
+	col   int
+}
+
+// line runs s through the terminal, returning it as it would be shown.
+func (t *term) line(s string) string {
+	if t.pen == (pen{}) && isPlain(s) {
+		return s
+	}
+
+	t.cells = t.cells[:0]
+	t.col = 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == 0x1b:
+			i = t.escape(s, i)
+			continue
+		case c == '\r':
+			t.col = 0
+		case c == '\b':
+			t.col = max(0, t.col-1)
+		case c == '\t':
+			t.col += 8 - t.col%8
+		case c < 0x20 || c == 0x7f:
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if unicode.In(r, unicode.Mn, unicode.Me) && t.col > 0 && t.col <= len(t.cells) {
+				// combining marks belong to the character before them
+				t.cells[t.col-1].s += string(r)
+			} else {
+				t.put(cell{s: string(r), pen: t.pen})
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return t.render()
+}
+
+// isPlain reports whether s has no escape sequences or control characters
+// that would need the terminal to work out.
+func isPlain(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// put writes c at the cursor and moves the cursor past it.
+func (t *term) put(c cell) {
+	for len(t.cells) < t.col {
+		t.cells = append(t.cells, blankCell)
+	}
+	if t.col < len(t.cells) {
+		t.cells[t.col] = c
+	} else {
+		t.cells = append(t.cells, c)
+	}
+	t.col++
+}
+
+// escape handles the escape sequence at s[i], returning the index after it.
+// OSC and other string sequences, such as title changes, are dropped so that
+// they can't leak through to the host terminal, including ones left
+// unterminated at the end of the line.
+func (t *term) escape(s string, i int) int {
+	if i+1 >= len(s) {
+		return len(s)
+	}
+
+	switch s[i+1] {
+	case '[':
+		j := i + 2
+		for j < len(s) && s[j] >= 0x30 && s[j] <= 0x3f {
+			j++
+		}
+		params := s[i+2 : j]
+		for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+			j++
+		}
+		if j >= len(s) {
+			return len(s)
+		}
+		t.csi(params, s[j])
+		return j + 1
+	case ']', 'P', '_', '^', 'X':
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == '\a' {
+				return j + 1
+			}
+			if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 2
+			}
+		}
+		return len(s)
+	default:
+		// a two character sequence, possibly with intermediate bytes,
+		// like the ones that pick character sets
+		j := i + 1
+		for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+			j++
+		}
+		return min(j+1, len(s))
+	}
+}
+
+// csi handles a control sequence with the given parameters and final byte.
+func (t *term) csi(params string, final byte) {
+	// sequences with a private marker, like hiding the cursor, don't
+	// change what is shown on the line
+	if params != "" && params[0] >= 0x3c {
+		return
+	}
+
+	n, err := strconv.Atoi(strings.SplitN(params, ";", 2)[0])
+	if err != nil {
+		n = 0
+	}
+	count := max(n, 1)
+
+	switch final {
+	case 'm':
+		t.pen.apply(params)
+	case 'K':
+		switch n {
+		case 0:
+			t.cells = t.cells[:min(t.col, len(t.cells))]
+		case 1:
+			for i := 0; i <= t.col && i < len(t.cells); i++ {
+				t.cells[i] = blankCell
+			}
+		case 2:
+			t.cells = t.cells[:0]
+		}
+	case 'X':
+		for i := t.col; i < t.col+count && i < len(t.cells); i++ {
+			t.cells[i] = blankCell
+		}
+	case 'P':
+		if t.col < len(t.cells) {
+			end := min(t.col+count, len(t.cells))
+			t.cells = append(t.cells[:t.col], t.cells[end:]...)
+		}
+	case 'C':
+		t.col += count
+	case 'D':
+		t.col = max(0, t.col-count)
+	case 'G':
+		t.col = count - 1
+	}
+}
+
+// render writes out the cells of the line, leaving off trailing blanks.
+func (t *term) render() string {
+	end := len(t.cells)
+	for end > 0 && t.cells[end-1] == blankCell {
+		end--
+	}
+
+	var sb strings.Builder
+	var current pen
+	for _, c := range t.cells[:end] {
+		if c.pen != current {
+			if current != (pen{}) {
+				sb.WriteString("\x1b[0m")
+			}
+			sb.WriteString(c.pen.sgr())
+			current = c.pen
+		}
+		sb.WriteString(c.s)
+	}
+	if current != (pen{}) {
+		sb.WriteString("\x1b[0m")
+	}
+	return sb.String()
+}
@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+func TestTermLine(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "listening on :8080", "listening on :8080"},
+		{"colors", "\x1b[31mred\x1b[0m plain", "\x1b[31mred\x1b[0m plain"},
+		{"carriage return", "50%\r100%", "100%"},
+		{"shorter overwrite", "downloading 10%\rdone", "doneloading 10%"},
+		{"erase line", "downloading 10%\r\x1b[Kdone", "done"},
+		{"erase whole line", "downloading\x1b[2K\rdone", "done"},
+		{"backspace", "abc\b\bX", "aXc"},
+		{"cursor forward", "a\x1b[5Cb", "a     b"},
+		{"tab", "a\tb", "a       b"},
+		{"column", "abcdef\x1b[3GX", "abXdef"},
+		{"private sequences", "\x1b[?25lspinner\x1b[?25h", "spinner"},
+		{"cursor up", "\x1b[2A\x1b[1Gup", "up"},
+		{"osc", "\x1b]0;title\aok\x1b]8;;https://example.com\x1b\\link", "oklink"},
+		{"unterminated osc", "ok\x1b]0;title", "ok"},
+		{"charset", "\x1b(Bok", "ok"},
+		{
+			"attributes",
+			"\x1b[1;38;5;208mhot\x1b[22mwarm\x1b[39m",
+			"\x1b[1;38;5;208mhot\x1b[0m\x1b[38;5;208mwarm\x1b[0m",
+		},
+		{"overwritten styles", "\x1b[31mred\r\x1b[32mgreen", "\x1b[32mgreen\x1b[0m"},
+		{"trailing style", "text\x1b[31m", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt := &term{}
+			if got := vt.line(tt.in); got != tt.want {
+				t.Errorf("line(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTermCarriesStyleAcrossLines(t *testing.T) {
+	vt := &term{}
+	lines := []string{"\x1b[1;31merror:", "  details", "\x1b[0mdone"}
+	want := []string{"\x1b[1;31merror:\x1b[0m", "\x1b[1;31m  details\x1b[0m", "done"}
+
+	for i, line := range lines {
+		if got := vt.line(line); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
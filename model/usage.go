@@ -0,0 +1,226 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// usageInterval is the time between two samples of CPU and memory usage.
+const usageInterval = time.Second
+
+// usageHistoryLen is the number of CPU samples kept for the sparkline.
+const usageHistoryLen = 20
+
+// clockTicks is the number of CPU time ticks per second /proc reports in,
+// USER_HZ, which is 100 on every Linux platform.
+const clockTicks = 100
+
+var errUsageUnsupported = errors.New("usage sampling is not supported on this platform")
+
+// usageSample is the CPU time and memory used by every process in a process
+// group at one moment, and the TCP ports they were listening on.
+type usageSample struct {
+	// ticks is the CPU time used, in clockTicks.
+	ticks uint64
+	rss   int64
+	ports []int
+}
+
+type usageMsg struct {
+	samples     map[uuid.UUID]usageSample
+	at          time.Time
+	unsupported bool
+}
+
+// processUsage is the CPU and memory a process's command and everything it
+// started are using, summed over its children for a group.
+type processUsage struct {
+	measured bool
+	// cpu is in percent of one core, so it goes above 100 for commands
+	// that keep several cores busy.
+	cpu     float64
+	rss     int64
+	history []float64
+	// ports are the TCP ports the command listens on, in ascending order.
+	ports []int
+
+	// ticks and sampled are from the previous sample, which the CPU usage
+	// is measured against.
+	ticks   uint64
+	sampled time.Time
+}
+
+// add records a new sample.
+func (u *processUsage) add(s usageSample, at time.Time) {
+	if !u.sampled.IsZero() && s.ticks >= u.ticks {
+		elapsed := at.Sub(u.sampled).Seconds()
+		if elapsed > 0 {
+			u.cpu = float64(s.ticks-u.ticks) / clockTicks / elapsed * 100
+		}
+		u.record()
+	}
+	u.measured = true
+	u.rss = s.rss
+	u.ports = s.ports
+	u.ticks = s.ticks
+	u.sampled = at
+}
+
+// record appends the current CPU usage to the history.
+func (u *processUsage) record() {
+	u.history = append(u.history, u.cpu)
+	if len(u.history) > usageHistoryLen {
+		u.history = u.history[len(u.history)-usageHistoryLen:]
+	}
+}
+
+// reset forgets the last sample, keeping the history, once nothing is left
+// running.
+func (u *processUsage) reset() {
+	u.measured = false
+	u.cpu = 0
+	u.rss = 0
+	u.ports = nil
+	u.ticks = 0
+	u.sampled = time.Time{}
+}
+
+// short is the CPU and memory usage for the process list.
+func (u *processUsage) short() string {
+	if !u.measured {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%% %s", u.cpu, formatBytes(u.rss))
+}
+
+// details is the CPU and memory usage, with the recent CPU history, for the
+// detail header.
+func (u *processUsage) details() string {
+	if !u.measured {
+		return ""
+	}
+	details := fmt.Sprintf("cpu %.1f%% %s · mem %s", u.cpu, sparkline(u.history), formatBytes(u.rss))
+	if len(u.ports) > 0 {
+		// the ports come first so that a narrow header doesn't cut them off
+		ports := make([]string, len(u.ports))
+		for i, port := range u.ports {
+			ports[i] = fmt.Sprintf(":%d", port)
+		}
+		details = strings.Join(ports, " ") + " · " + details
+	}
+	return details
+}
+
+// sampleUsage measures every running process once usageInterval has passed.
+func (m *processList) sampleUsage() tea.Cmd {
+	pgids := make(map[uuid.UUID]int)
+	for _, l := range m.leaves() {
+		if l.status.isActive() && l.run.running() {
+			// every command leads its own process group
+			pgids[l.id] = l.run.pid
+		}
+	}
+
+	return tea.Tick(usageInterval, func(t time.Time) tea.Msg {
+		samples, err := readUsage(pgids)
+		if errors.Is(err, errUsageUnsupported) {
+			return usageMsg{unsupported: true}
+		}
+		return usageMsg{samples: samples, at: time.Now()}
+	})
+}
+
+// applyUsage stores the samples on the processes they were taken for, and
+// sums them up for the groups.
+func (m *processList) applyUsage(msg usageMsg) {
+	for _, l := range m.leaves() {
+		if s, ok := msg.samples[l.id]; ok {
+			l.usage.add(s, msg.at)
+		} else {
+			l.usage.reset()
+		}
+
+		if l.readyOnPort && l.status == statusRunning && len(l.usage.ports) > 0 {
+			l.logEvent(logEntry{
+				msg:   fmt.Sprintf("listening on port %d", l.usage.ports[0]),
+				level: logInfo,
+			})
+			l.status = statusReady
+			l.startLivenessCheck()
+		}
+	}
+
+	var sum func(p *process)
+	sum = func(p *process) {
+		if !p.isGroup {
+			return
+		}
+
+		var (
+			cpu      float64
+			rss      int64
+			measured bool
+		)
+		for _, cp := range p.children {
+			sum(cp)
+			if cp.usage.measured {
+				measured = true
+				cpu += cp.usage.cpu
+				rss += cp.usage.rss
+			}
+		}
+		if !measured {
+			p.usage.reset()
+			return
+		}
+		p.usage.measured = true
+		p.usage.cpu = cpu
+		p.usage.rss = rss
+		p.usage.record()
+	}
+	// the all entry's children are the top-level processes
+	sum(m.all)
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws CPU usage values, scaled so that a full bar is one busy
+// core, or the highest value if that is more.
+func sparkline(values []float64) string {
+	top := 100.0
+	for _, v := range values {
+		top = max(top, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := int(v / top * float64(len(sparks)-1))
+		sb.WriteRune(sparks[max(0, min(i, len(sparks)-1))])
+	}
+	return sb.String()
+}
+
+// formatBytes formats a size with a binary unit, e.g. "340M" or "1.2G".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	value, suffix := float64(n)/unit, "K"
+	for _, s := range []string{"M", "G", "T"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, suffix)
+	}
+	return fmt.Sprintf("%.0f%s", value, suffix)
+}
@@ -0,0 +1,184 @@
+//go:build linux
+
+package model
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// usageSupported reports whether readUsage works on this platform.
+const usageSupported = true
+
+// readUsage sums the CPU time and resident memory of every process in each
+// of the given process groups, and finds the TCP ports they listen on, all
+// read from /proc.
+func readUsage(pgids map[uuid.UUID]int) (map[uuid.UUID]usageSample, error) {
+	samples := make(map[uuid.UUID]usageSample, len(pgids))
+	if len(pgids) == 0 {
+		return samples, nil
+	}
+
+	byPgid := make(map[int]uuid.UUID, len(pgids))
+	for id, pgid := range pgids {
+		byPgid[pgid] = id
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int64(os.Getpagesize())
+	sockets := make(map[uint64]uuid.UUID)
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		// processes may exit while we look at them
+		data, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		pgrp, ticks, pages, ok := parseStat(data)
+		if !ok {
+			continue
+		}
+		id, ok := byPgid[pgrp]
+		if !ok {
+			continue
+		}
+
+		s := samples[id]
+		s.ticks += ticks
+		s.rss += pages * pageSize
+		samples[id] = s
+
+		for _, inode := range socketInodes(e.Name()) {
+			sockets[inode] = id
+		}
+	}
+
+	if len(sockets) == 0 {
+		return samples, nil
+	}
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(table)
+		if err != nil {
+			continue
+		}
+		for inode, port := range parseListening(data) {
+			id, ok := sockets[inode]
+			if !ok {
+				continue
+			}
+			s := samples[id]
+			if !slices.Contains(s.ports, port) {
+				s.ports = append(s.ports, port)
+				slices.Sort(s.ports)
+			}
+			samples[id] = s
+		}
+	}
+	return samples, nil
+}
+
+// socketInodes returns the inodes of the sockets the process with the given
+// pid has open.
+func socketInodes(pid string) []uint64 {
+	dir := "/proc/" + pid + "/fd/"
+	fds, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var inodes []uint64
+	for _, fd := range fds {
+		link, err := os.Readlink(dir + fd.Name())
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes
+}
+
+// tcpListen is the state of a listening socket in /proc/net/tcp.
+const tcpListen = "0A"
+
+// parseListening maps the inode of every listening socket in the contents of
+// /proc/net/tcp or /proc/net/tcp6 to its local port.
+func parseListening(data []byte) map[uint64]int {
+	listening := make(map[uint64]int)
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		fields := bytes.Fields(line)
+		// the first line holds the column names
+		if i == 0 || len(fields) < 10 || string(fields[3]) != tcpListen {
+			continue
+		}
+
+		_, hexPort, ok := bytes.Cut(fields[1], []byte(":"))
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(string(hexPort), 16, 16)
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(string(fields[9]), 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		listening[inode] = int(port)
+	}
+	return listening
+}
+
+// parseStat reads the process group, the CPU time spent in user and kernel
+// mode, and the resident set size in pages from the contents of
+// /proc/<pid>/stat.
+func parseStat(data []byte) (pgrp int, ticks uint64, pages int64, ok bool) {
+	// the command name in parentheses may contain spaces and parentheses
+	// itself, so the fields are counted from the last ')'
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, 0, 0, false
+	}
+	fields := bytes.Fields(data[end+1:])
+	// fields[0] is the state, field 3 of the file
+	const (
+		pgrpField  = 5 - 3
+		utimeField = 14 - 3
+		stimeField = 15 - 3
+		rssField   = 24 - 3
+	)
+	if len(fields) <= rssField {
+		return 0, 0, 0, false
+	}
+
+	pgrp, err := strconv.Atoi(string(fields[pgrpField]))
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	utime, err := strconv.ParseUint(string(fields[utimeField]), 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	stime, err := strconv.ParseUint(string(fields[stimeField]), 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	pages, err = strconv.ParseInt(string(fields[rssField]), 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return pgrp, utime + stime, pages, true
+}
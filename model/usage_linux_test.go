@@ -0,0 +1,60 @@
+package model
+
+import (
+	"net"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseStat(t *testing.T) {
+	data := []byte("4242 (my (odd) cmd) S 1 4240 4240 0 -1 4194304 1234 0 0 0 150 25 0 0 20 0 3 0 123456 104857600 2560 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n")
+
+	pgrp, ticks, pages, ok := parseStat(data)
+	if !ok {
+		t.Fatal("parseStat failed")
+	}
+	if pgrp != 4240 || ticks != 175 || pages != 2560 {
+		t.Fatalf("unexpected values: pgrp=%d ticks=%d pages=%d", pgrp, ticks, pages)
+	}
+
+	if _, _, _, ok := parseStat([]byte("4242 (cmd) S 1")); ok {
+		t.Fatal("expected a truncated stat line to fail")
+	}
+}
+
+func TestParseListening(t *testing.T) {
+	data := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0BB8 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 5151 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 5252 1 0000000000000000 20 4 30 10 -1
+`)
+
+	listening := parseListening(data)
+	if len(listening) != 1 || listening[5151] != 3000 {
+		t.Fatalf("expected only port 3000 to be listening, got %v", listening)
+	}
+}
+
+func TestReadUsageOwnGroup(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	id := uuid.New()
+	samples, err := readUsage(map[uuid.UUID]int{id: syscall.Getpgrp()})
+	if err != nil {
+		t.Fatalf("readUsage returned error: %v", err)
+	}
+	if samples[id].rss <= 0 {
+		t.Fatalf("expected the test's own process group to use memory, got %+v (pid %d)", samples[id], os.Getpid())
+	}
+	if !slices.Contains(samples[id].ports, port) {
+		t.Fatalf("expected port %d among the listening ports, got %v", port, samples[id].ports)
+	}
+}
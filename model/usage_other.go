@@ -0,0 +1,13 @@
+//go:build !linux
+
+package model
+
+import "github.com/google/uuid"
+
+// usageSupported reports whether readUsage works on this platform.
+const usageSupported = false
+
+// readUsage is only implemented on Linux, where it reads /proc.
+func readUsage(pgids map[uuid.UUID]int) (map[uuid.UUID]usageSample, error) {
+	return nil, errUsageUnsupported
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func TestProcessUsageAdd(t *testing.T) {
+	var u processUsage
+	at := time.Now()
+
+	u.add(usageSample{ticks: 1000, rss: 1 << 20}, at)
+	if !u.measured || u.cpu != 0 || len(u.history) != 0 {
+		t.Fatalf("expected the first sample to only set a baseline, got %+v", u)
+	}
+
+	// 50 ticks over half a second is a whole core
+	u.add(usageSample{ticks: 1050, rss: 2 << 20}, at.Add(500*time.Millisecond))
+	if u.cpu != 100 || u.rss != 2<<20 || len(u.history) != 1 {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+	if got := u.short(); got != "100% 2.0M" {
+		t.Fatalf("unexpected short usage: %q", got)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]float64{0, 50, 100}); got != "▁▄█" {
+		t.Fatalf("unexpected sparkline: %q", got)
+	}
+	// values above one core rescale the whole line
+	if got := sparkline([]float64{100, 200}); got != "▄█" {
+		t.Fatalf("unexpected sparkline: %q", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:              "512B",
+		1536:             "1.5K",
+		340 << 20:        "340M",
+		3 << 30:          "3.0G",
+		(5 << 40) + 1000: "5.0T",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRedrawKeepsTickingWithoutUsage(t *testing.T) {
+	pl := newProcessList(config.Config{Processes: []config.ProcessConfig{
+		{Name: "api", Command: []string{"api"}},
+	}})
+
+	pl.Update(usageMsg{unsupported: true})
+	if pl.redrawing {
+		t.Fatal("expected no redraws while nothing runs")
+	}
+
+	pl.byName["api"].status = statusRunning
+	pl.Update(usageMsg{unsupported: true})
+	if !pl.redrawing {
+		t.Fatal("expected redraws while a process runs, even without usage")
+	}
+
+	pl.byName["api"].status = statusExited
+	pl.Update(redrawMsg{})
+	if pl.redrawing {
+		t.Fatal("expected redraws to stop once every process stopped")
+	}
+}
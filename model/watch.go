@@ -0,0 +1,307 @@
+package model
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/steventhorne/sheepdog/config"
+	"github.com/steventhorne/sheepdog/control"
+)
+
+const (
+	watchRestart = "restart"
+	watchRun     = "run"
+)
+
+// defaultWatchDebounce is how long the files have to stay unchanged before
+// the process is restarted, so that saving many files at once only restarts
+// it once.
+const defaultWatchDebounce = 300 * time.Millisecond
+
+// watchPollInterval is the time between two scans of the tree when changes
+// can't be watched natively.
+const watchPollInterval = time.Second
+
+// DebugLogName is the name of the file sheepdog writes its own log to, next
+// to the config file.
+const DebugLogName = ".sheepdog.log"
+
+// watcher restarts or re-runs a process when files under its cwd change.
+type watcher struct {
+	include  []string
+	exclude  []string
+	debounce time.Duration
+	action   string
+	// ignoreNames are the names of files sheepdog writes itself, which
+	// never trigger the action wherever they are.
+	ignoreNames []string
+	// logFiles are the absolute paths of the log files of every process,
+	// which never trigger the action either, nor do their rotated copies.
+	logFiles []string
+}
+
+func newWatcher(conf *config.WatchConfig) (*watcher, error) {
+	w := &watcher{
+		include:     conf.Include,
+		exclude:     conf.Exclude,
+		debounce:    defaultWatchDebounce,
+		action:      watchRestart,
+		ignoreNames: []string{DebugLogName, control.SocketName},
+	}
+
+	for _, pattern := range append(append([]string{}, conf.Include...), conf.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+
+	switch conf.Action {
+	case "", watchRestart:
+	case watchRun:
+		w.action = watchRun
+	default:
+		return nil, fmt.Errorf("invalid action %q", conf.Action)
+	}
+
+	if conf.Debounce > 0 {
+		w.debounce = time.Duration(conf.Debounce)
+	}
+
+	return w, nil
+}
+
+// matches reports whether a change to the file at rel, relative to the
+// watched directory, should trigger the action.
+func (w *watcher) matches(rel string) bool {
+	for _, pattern := range w.exclude {
+		if matchGlob(pattern, rel) {
+			return false
+		}
+	}
+
+	if len(w.include) == 0 {
+		return true
+	}
+	for _, pattern := range w.include {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored reports whether the file at name, an absolute path, is one that
+// sheepdog writes itself. Watching those would restart a process every time
+// sheepdog logs something.
+func (w *watcher) ignored(name string) bool {
+	if slices.Contains(w.ignoreNames, filepath.Base(name)) {
+		return true
+	}
+	name = filepath.Clean(name)
+	for _, lf := range w.logFiles {
+		if name == lf {
+			return true
+		}
+		// rotated copies are named <file>.1, <file>.2 and so on
+		if n, ok := strings.CutPrefix(name, lf+"."); ok && n != "" && strings.Trim(n, "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDir reports whether nothing below the directory at rel is watched.
+func (w *watcher) skipDir(rel string) bool {
+	if path.Base(rel) == ".git" {
+		return true
+	}
+	for _, pattern := range w.exclude {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch watches the tree under root and sends on changed, without blocking,
+// once matching files have changed and then stayed quiet for the debounce
+// interval. It falls back to polling when the tree can't be watched natively,
+// and runs until the program exits.
+func (w *watcher) watch(root string, changed chan<- string) {
+	events := make(chan string, 64)
+	if err := watchTree(root, w.skipDir, events); err != nil {
+		go pollTree(root, w.skipDir, events)
+	}
+
+	go func() {
+		var (
+			timer <-chan time.Time
+			last  string
+		)
+		for {
+			select {
+			case name := <-events:
+				if w.ignored(name) {
+					continue
+				}
+				rel, err := filepath.Rel(root, name)
+				if err != nil || !w.matches(filepath.ToSlash(rel)) {
+					continue
+				}
+				last = filepath.ToSlash(rel)
+				timer = time.After(w.debounce)
+			case <-timer:
+				timer = nil
+				select {
+				case changed <- last:
+				default:
+				}
+			}
+		}
+	}()
+}
+
+// pollTree scans the tree under root for changes every watchPollInterval and
+// sends the path of every file that was created, modified or removed.
+func pollTree(root string, skipDir func(rel string) bool, events chan<- string) {
+	scan := func() map[string]time.Time {
+		files := make(map[string]time.Time)
+		filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if rel, err := filepath.Rel(root, name); err == nil && rel != "." && skipDir(filepath.ToSlash(rel)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				files[name] = info.ModTime()
+			}
+			return nil
+		})
+		return files
+	}
+
+	files := scan()
+	for range time.Tick(watchPollInterval) {
+		next := scan()
+		for name, mod := range next {
+			if prev, ok := files[name]; !ok || !prev.Equal(mod) {
+				events <- name
+			}
+		}
+		for name := range files {
+			if _, ok := next[name]; !ok {
+				events <- name
+			}
+		}
+		files = next
+	}
+}
+
+// matchGlob reports whether the slash-separated path name matches pattern.
+// On top of the path.Match syntax, a "**" segment matches any number of
+// directories. A pattern without a slash is matched against the base name.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+type watchMsg struct {
+	id   uuid.UUID
+	file string
+}
+
+// waitForChange waits for the watcher of a process to report a change.
+func waitForChange(id uuid.UUID, changed <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		return watchMsg{id: id, file: <-changed}
+	}
+}
+
+// startWatching starts watching the files of a process that has a watch
+// block.
+func (m *process) startWatching() tea.Cmd {
+	if m.watch == nil || m.watchCh != nil {
+		return nil
+	}
+
+	root, err := m.resolvePath(m.cwd)
+	if err != nil {
+		m.logEvent(logEntry{
+			msg:   fmt.Sprintf("unable to watch files: %v", err),
+			level: logError,
+		})
+		return nil
+	}
+	if _, err := os.Stat(root); err != nil {
+		m.logEvent(logEntry{
+			msg:   fmt.Sprintf("unable to watch files: %v", err),
+			level: logError,
+		})
+		return nil
+	}
+
+	m.watchCh = make(chan string, 1)
+	m.watch.watch(root, m.watchCh)
+	return waitForChange(m.id, m.watchCh)
+}
+
+// onChange restarts the process, or for the run action runs it again once
+// its current run is over, after its files changed. Processes that were
+// never started, or that were stopped, are left alone.
+func (m *process) onChange(file string) tea.Cmd {
+	if m.status == statusIdle || m.stopRequested {
+		return nil
+	}
+
+	if m.watch.action == watchRun && m.status.isActive() {
+		m.logEvent(logEntry{
+			msg:   fmt.Sprintf("%s changed, running again once this run is over", file),
+			level: logInfo,
+		})
+		m.restartAfterStop = true
+		return nil
+	}
+
+	m.logEvent(logEntry{
+		msg:   fmt.Sprintf("%s changed, restarting", file),
+		level: logInfo,
+	})
+	return m.Restart()
+}
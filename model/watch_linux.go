@@ -0,0 +1,89 @@
+//go:build linux
+
+package model
+
+import (
+	"io/fs"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MODIFY |
+	unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// watchTree watches every directory under root with inotify and sends the
+// path of every file that changes. Directories created later are watched as
+// they appear. It fails when inotify is unavailable or out of watches.
+func watchTree(root string, skipDir func(rel string) bool, events chan<- string) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+
+	dirs := make(map[int]string)
+	addTree := func(dir string) error {
+		return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if rel, err := filepath.Rel(root, name); err == nil && rel != "." && skipDir(filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+
+			wd, err := unix.InotifyAddWatch(fd, name, inotifyMask)
+			if err != nil {
+				return err
+			}
+			dirs[wd] = name
+			return nil
+		})
+	}
+
+	if err := addTree(root); err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := unix.Read(fd, buf)
+			if err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				return
+			}
+
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+				offset += unix.SizeofInotifyEvent + int(event.Len)
+
+				dir, ok := dirs[int(event.Wd)]
+				if !ok {
+					continue
+				}
+				if event.Mask&unix.IN_IGNORED != 0 {
+					delete(dirs, int(event.Wd))
+					continue
+				}
+
+				name := filepath.Join(dir, unix.ByteSliceToString(nameBytes))
+				if event.Mask&unix.IN_ISDIR != 0 {
+					if event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+						// a failure here only leaves the new directory
+						// unwatched
+						addTree(name)
+					}
+					continue
+				}
+				events <- name
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package model
+
+import "errors"
+
+// watchTree is only implemented on Linux; everywhere else the tree is polled.
+func watchTree(root string, skipDir func(rel string) bool, events chan<- string) error {
+	return errors.New("native file watching is not supported on this platform")
+}
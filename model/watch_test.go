@@ -0,0 +1,184 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/server/main.go", true},
+		{"*.go", "main.rs", false},
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/sub/main.go", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"**/*_test.go", "pkg/x_test.go", true},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/a/b.go", true},
+		{"vendor/**", "src/vendor/a.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWatcherMatches(t *testing.T) {
+	w, err := newWatcher(&config.WatchConfig{
+		Include: []string{"**/*.go"},
+		Exclude: []string{"**/*_test.go", "node_modules"},
+	})
+	if err != nil {
+		t.Fatalf("newWatcher returned error: %v", err)
+	}
+
+	if !w.matches("cmd/main.go") {
+		t.Error("expected cmd/main.go to match")
+	}
+	if w.matches("cmd/main_test.go") {
+		t.Error("expected cmd/main_test.go to be excluded")
+	}
+	if w.matches("README.md") {
+		t.Error("expected README.md not to be included")
+	}
+	if !w.skipDir("web/node_modules") || !w.skipDir(".git") || w.skipDir("cmd") {
+		t.Error("unexpected skipped directories")
+	}
+
+	if _, err := newWatcher(&config.WatchConfig{Action: "reload"}); err == nil {
+		t.Fatal("expected error for invalid action, got nil")
+	}
+	if _, err := newWatcher(&config.WatchConfig{Include: []string{"[a-"}}); err == nil {
+		t.Fatal("expected error for invalid pattern, got nil")
+	}
+}
+
+func TestWatcherDebouncesChanges(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	w, err := newWatcher(&config.WatchConfig{
+		Include:  []string{"src/*.go"},
+		Debounce: config.Duration(50 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("newWatcher returned error: %v", err)
+	}
+
+	changed := make(chan string, 1)
+	w.watch(root, changed)
+
+	for _, name := range []string{"notes.txt", "src/a.go", "src/b.go"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	select {
+	case file := <-changed:
+		if file != "src/b.go" {
+			t.Fatalf("expected src/b.go to be reported, got %q", file)
+		}
+	case <-time.After(3 * watchPollInterval):
+		t.Fatal("timed out waiting for a change")
+	}
+
+	select {
+	case file := <-changed:
+		t.Fatalf("expected a single change, got another for %q", file)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestPollTreeReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	events := make(chan string, 8)
+	go pollTree(root, func(string) bool { return false }, events)
+
+	// give the first scan time to finish before the file appears
+	time.Sleep(100 * time.Millisecond)
+	name := filepath.Join(root, "main.go")
+	if err := os.WriteFile(name, []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	select {
+	case got := <-events:
+		if got != name {
+			t.Fatalf("expected %q to be reported, got %q", name, got)
+		}
+	case <-time.After(3 * watchPollInterval):
+		t.Fatal("timed out waiting for a change")
+	}
+}
+
+func TestWatcherIgnoresSheepdogFiles(t *testing.T) {
+	root := t.TempDir()
+	pl := newProcessList(config.Config{
+		Dir:    root,
+		LogDir: "logs",
+		Processes: []config.ProcessConfig{
+			{Name: "api", Command: []string{"api"}, Watch: &config.WatchConfig{}},
+			{Name: "worker", Command: []string{"worker"}, LogFile: "worker.out"},
+		},
+	})
+	w := pl.byName["api"].watch
+
+	for _, name := range []string{
+		"logs/api.log",
+		"logs/api.log.1",
+		"logs/api.log.12",
+		"worker.out",
+		"worker.out.3",
+		".sheepdog.log",
+		".sheepdog.sock",
+		"sub/.sheepdog.log",
+	} {
+		if !w.ignored(filepath.Join(root, name)) {
+			t.Errorf("expected %s to be ignored", name)
+		}
+	}
+	for _, name := range []string{"main.go", "logs/api.log.bak", "worker.out.x", "logs/other.log"} {
+		if w.ignored(filepath.Join(root, name)) {
+			t.Errorf("expected %s to be watched", name)
+		}
+	}
+}
+
+func TestWatcherDoesNotReportLogWrites(t *testing.T) {
+	root := t.TempDir()
+	w, err := newWatcher(&config.WatchConfig{Debounce: config.Duration(50 * time.Millisecond)})
+	if err != nil {
+		t.Fatalf("newWatcher returned error: %v", err)
+	}
+	w.logFiles = []string{filepath.Join(root, "api.log")}
+
+	changed := make(chan string, 1)
+	w.watch(root, changed)
+
+	for _, name := range []string{"api.log", "api.log.1", DebugLogName} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	select {
+	case file := <-changed:
+		t.Fatalf("expected log writes to be ignored, got a change for %q", file)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
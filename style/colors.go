@@ -14,8 +14,24 @@ var (
 	colorGray      = lipgloss.Color("#535965")
 	colorLightGray = lipgloss.Color("#7a818e")
 
-	colorIdle    = colorFg
-	colorRunning = colorYellow
-	colorReady   = colorGreen
-	colorErrored = colorRed
+	colorIdle      = colorFg
+	colorRunning   = colorYellow
+	colorReady     = colorGreen
+	colorWaiting   = colorBlue
+	colorUnhealthy = colorPurple
+	colorStopping  = colorOrange
+	colorErrored   = colorRed
+
+	colorSearchMatch = colorYellow
 )
+
+// ProcessColors is the palette used to tell processes apart when their output
+// is shown together.
+var ProcessColors = []lipgloss.Color{
+	colorCyan,
+	colorYellow,
+	colorPurple,
+	colorGreen,
+	colorBlue,
+	colorOrange,
+}
@@ -3,7 +3,7 @@ package style
 const (
 	WidthSidenav         = 50
 	WidthViewportOffset  = 0 - WidthSidenav - 6
-	HeightViewportOffset = 0 - 4
+	HeightViewportOffset = 0 - 5
 	PaddingDetails       = 2
 	PaddingDetailsTotal  = PaddingDetails * 2
 	PaddingSidenav       = 2
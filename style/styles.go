@@ -29,9 +29,19 @@ var (
 				Foreground(colorRunning)
 	StyleItemReady = StyleItem.
 			Foreground(colorReady)
+	StyleItemWaiting = StyleItem.
+				Foreground(colorWaiting)
+	StyleItemUnhealthy = StyleItem.
+				Foreground(colorUnhealthy)
+	StyleItemStopping = StyleItem.
+				Foreground(colorStopping)
 	StyleItemErrored = StyleItem.
 				Foreground(colorErrored)
 
+	StyleSearchMatch = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#000000")).
+				Background(colorSearchMatch)
+
 	StyleEnum = lipgloss.NewStyle().
 			MarginRight(1)
 	StyleEnumIdle = StyleEnum.
@@ -40,6 +50,12 @@ var (
 				Foreground(colorRunning)
 	StyleEnumReady = StyleEnum.
 			Foreground(colorReady)
+	StyleEnumWaiting = StyleEnum.
+				Foreground(colorWaiting)
+	StyleEnumUnhealthy = StyleEnum.
+				Foreground(colorUnhealthy)
+	StyleEnumStopping = StyleEnum.
+				Foreground(colorStopping)
 	StyleEnumErrored = StyleEnum.
 				Foreground(colorErrored)
 )
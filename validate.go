@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/steventhorne/sheepdog/config"
+)
+
+// runValidate implements `sheepdog validate`, which checks the given config
+// files, or the one sheepdog would use, without running anything.
+func runValidate(configPath string, findErr error, args []string) int {
+	if len(args) == 0 {
+		if findErr != nil {
+			fmt.Fprintf(os.Stderr, "sheepdog: %v\n", findErr)
+			return 1
+		}
+		args = []string{configPath}
+	}
+
+	code := 0
+	for _, path := range args {
+		if _, ok := loadConfig(os.Stderr, path); !ok {
+			code = 1
+			continue
+		}
+		fmt.Printf("%s: ok\n", path)
+	}
+	return code
+}
+
+// loadConfig loads and validates the config at path, writing every problem
+// it finds to w. It reports whether the config is usable.
+func loadConfig(w io.Writer, path string) (config.Config, bool) {
+	conf, err := config.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(w, "sheepdog: %v\n", err)
+		return conf, false
+	}
+
+	if err := config.Validate(conf); err != nil {
+		var validationErr *config.ValidationError
+		if !errors.As(err, &validationErr) {
+			fmt.Fprintf(w, "sheepdog: %s: %v\n", path, err)
+			return conf, false
+		}
+		for _, p := range validationErr.Problems {
+			fmt.Fprintf(w, "%s: %s\n", path, p)
+		}
+		return conf, false
+	}
+
+	return conf, true
+}